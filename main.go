@@ -1,16 +1,21 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/santoshkal/gomcp/pkg/reg"
 	"github.com/santoshkal/gomcp/pkg/server"
 )
 
 func main() {
 	// Optionally allow overriding the config file path.
 	configPath := flag.String("config", "", "Path to the configuration YAML file")
+	transport := flag.String("transport", "", "Transport to serve MCP on: http or stdio (default from server.transport, else http)")
 	flag.Parse()
 
 	// If a config path is provided, set the environment variable.
@@ -18,10 +23,47 @@ func main() {
 		os.Setenv("MCP_CONFIG_PATH", *configPath)
 	}
 
+	// "gomcp validate" checks the configuration and exits.
+	if flag.Arg(0) == "validate" {
+		os.Exit(runValidate(flag.Args()[1:]))
+	}
+
+	// The transport must be known before the server loads plugins, so the
+	// server settings are read ahead of NewServer.
+	if *transport == "" {
+		settings, _ := reg.LoadServerConfig(reg.ResolveConfigPath())
+		*transport = settings.Transport
+	}
+
+	// Over stdio, stdout carries protocol messages only, so anything else that
+	// writes to os.Stdout (plugin loading, handlers) is sent to stderr instead.
+	stdout := os.Stdout
+	if *transport == "stdio" {
+		os.Stdout = os.Stderr
+	}
+
 	srv, err := server.NewServer()
 	if err != nil {
 		log.Fatalf("Error initializing server: %v", err)
 	}
 
-	srv.StartRPCServer()
+	// SIGHUP reloads the configuration without dropping clients.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			srv.ReloadConfig()
+		}
+	}()
+
+	switch *transport {
+	case "stdio":
+		if err := srv.ServeStdio(context.Background(), os.Stdin, stdout); err != nil {
+			log.Fatalf("Error serving stdio: %v", err)
+		}
+	case "http":
+		srv.StartRPCServer()
+	default:
+		log.Fatalf("Unknown transport %q: expected http or stdio", *transport)
+	}
 }
@@ -0,0 +1,402 @@
+package mcp
+
+import "encoding/json"
+
+// LatestProtocolVersion is the newest MCP protocol revision supported by the server.
+const LatestProtocolVersion = "2025-03-26"
+
+// SupportedProtocolVersions lists the MCP protocol revisions the server accepts, newest first.
+var SupportedProtocolVersions = []string{LatestProtocolVersion, "2024-11-05"}
+
+// MCP method and notification names.
+const (
+	MethodInitialize             = "initialize"
+	MethodPing                   = "ping"
+	NotificationInitialized      = "notifications/initialized"
+	NotificationCancelled        = "notifications/cancelled"
+	NotificationProgress         = "notifications/progress"
+	MethodToolsList              = "tools/list"
+	MethodToolsCall              = "tools/call"
+	NotificationToolsListChanged = "notifications/tools/list_changed"
+
+	MethodResourcesList              = "resources/list"
+	MethodResourceTemplatesList      = "resources/templates/list"
+	MethodResourcesRead              = "resources/read"
+	MethodResourcesSubscribe         = "resources/subscribe"
+	MethodResourcesUnsubscribe       = "resources/unsubscribe"
+	NotificationResourceUpdated      = "notifications/resources/updated"
+	NotificationResourcesListChanged = "notifications/resources/list_changed"
+
+	MethodLoggingSetLevel      = "logging/setLevel"
+	NotificationLoggingMessage = "notifications/message"
+
+	MethodCompletionComplete = "completion/complete"
+
+	MethodSamplingCreateMessage = "sampling/createMessage"
+
+	MethodRootsList              = "roots/list"
+	NotificationRootsListChanged = "notifications/roots/list_changed"
+
+	MethodPromptsList              = "prompts/list"
+	MethodPromptsGet               = "prompts/get"
+	NotificationPromptsListChanged = "notifications/prompts/list_changed"
+)
+
+// Implementation describes the name and version of an MCP client or server.
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// ClientCapabilities describes the optional features a client supports.
+type ClientCapabilities struct {
+	Roots        *RootsCapability       `json:"roots,omitempty"`
+	Sampling     map[string]interface{} `json:"sampling,omitempty"`
+	Experimental map[string]interface{} `json:"experimental,omitempty"`
+}
+
+// RootsCapability is advertised by clients that can provide filesystem roots.
+type RootsCapability struct {
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+// ServerCapabilities describes the optional features the server supports.
+type ServerCapabilities struct {
+	Tools       *ToolsCapability       `json:"tools,omitempty"`
+	Resources   *ResourcesCapability   `json:"resources,omitempty"`
+	Prompts     *PromptsCapability     `json:"prompts,omitempty"`
+	Logging     *LoggingCapability     `json:"logging,omitempty"`
+	Completions *CompletionsCapability `json:"completions,omitempty"`
+}
+
+// ToolsCapability is present when the server offers tools.
+type ToolsCapability struct {
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+// ResourcesCapability is present when the server offers resources.
+type ResourcesCapability struct {
+	Subscribe   bool `json:"subscribe,omitempty"`
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+// PromptsCapability is present when the server offers prompt templates.
+type PromptsCapability struct {
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+// LoggingCapability is present when the server can send log messages to the client.
+type LoggingCapability struct{}
+
+// CompletionsCapability is present when the server offers argument completions.
+type CompletionsCapability struct{}
+
+// InitializeParams are the parameters of the initialize request.
+type InitializeParams struct {
+	ProtocolVersion string             `json:"protocolVersion"`
+	Capabilities    ClientCapabilities `json:"capabilities"`
+	ClientInfo      Implementation     `json:"clientInfo"`
+}
+
+// InitializeResult is the server's reply to the initialize request.
+type InitializeResult struct {
+	ProtocolVersion string             `json:"protocolVersion"`
+	Capabilities    ServerCapabilities `json:"capabilities"`
+	ServerInfo      Implementation     `json:"serverInfo"`
+	Instructions    string             `json:"instructions,omitempty"`
+}
+
+// IsSupportedProtocolVersion reports whether the given protocol revision is supported.
+func IsSupportedProtocolVersion(version string) bool {
+	for _, v := range SupportedProtocolVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// Tool describes a tool as advertised by tools/list.
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+// PaginatedParams carries the opaque cursor of a paginated list request.
+type PaginatedParams struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// ListToolsResult is the reply to tools/list.
+type ListToolsResult struct {
+	Tools      []Tool `json:"tools"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// RequestMeta is the _meta object a client may attach to a request.
+type RequestMeta struct {
+	// ProgressToken is a string or number; when set the client wants progress notifications.
+	ProgressToken json.RawMessage `json:"progressToken,omitempty"`
+}
+
+// CallToolParams are the parameters of the tools/call request.
+type CallToolParams struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Meta      *RequestMeta           `json:"_meta,omitempty"`
+}
+
+// Content is a single part of a tool result or prompt message.
+type Content struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+// CallToolResult is the reply to tools/call. Tool failures are reported with IsError set.
+type CallToolResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// NewTextContent returns a text content part.
+func NewTextContent(text string) Content {
+	return Content{Type: "text", Text: text}
+}
+
+// CancelledParams are the parameters of notifications/cancelled.
+type CancelledParams struct {
+	RequestID ID     `json:"requestId"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// ProgressParams are the parameters of notifications/progress.
+type ProgressParams struct {
+	ProgressToken json.RawMessage `json:"progressToken"`
+	Progress      float64         `json:"progress"`
+	Total         float64         `json:"total,omitempty"`
+	Message       string          `json:"message,omitempty"`
+}
+
+// Resource describes a concrete resource as advertised by resources/list.
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ResourceTemplate describes a parameterized resource as advertised by resources/templates/list.
+type ResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ListResourcesResult is the reply to resources/list.
+type ListResourcesResult struct {
+	Resources  []Resource `json:"resources"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+// ListResourceTemplatesResult is the reply to resources/templates/list.
+type ListResourceTemplatesResult struct {
+	ResourceTemplates []ResourceTemplate `json:"resourceTemplates"`
+	NextCursor        string             `json:"nextCursor,omitempty"`
+}
+
+// ResourceParams identify a resource by URI. They are used by resources/read,
+// resources/subscribe, resources/unsubscribe and notifications/resources/updated.
+type ResourceParams struct {
+	URI string `json:"uri"`
+}
+
+// ResourceContents holds the contents of a resource as text or base64 encoded blob.
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+// ReadResourceResult is the reply to resources/read.
+type ReadResourceResult struct {
+	Contents []ResourceContents `json:"contents"`
+}
+
+// PromptArgument describes an argument accepted by a prompt template.
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// Prompt describes a prompt template as advertised by prompts/list.
+type Prompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+// ListPromptsResult is the reply to prompts/list.
+type ListPromptsResult struct {
+	Prompts    []Prompt `json:"prompts"`
+	NextCursor string   `json:"nextCursor,omitempty"`
+}
+
+// GetPromptParams are the parameters of the prompts/get request.
+type GetPromptParams struct {
+	Name      string            `json:"name"`
+	Arguments map[string]string `json:"arguments,omitempty"`
+}
+
+// PromptMessage is a single rendered message of a prompt.
+type PromptMessage struct {
+	Role    string  `json:"role"`
+	Content Content `json:"content"`
+}
+
+// GetPromptResult is the reply to prompts/get.
+type GetPromptResult struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+// LoggingLevel is the severity of a log message, following syslog (RFC 5424).
+type LoggingLevel string
+
+// Logging levels in increasing order of severity.
+const (
+	LevelDebug     LoggingLevel = "debug"
+	LevelInfo      LoggingLevel = "info"
+	LevelNotice    LoggingLevel = "notice"
+	LevelWarning   LoggingLevel = "warning"
+	LevelError     LoggingLevel = "error"
+	LevelCritical  LoggingLevel = "critical"
+	LevelAlert     LoggingLevel = "alert"
+	LevelEmergency LoggingLevel = "emergency"
+)
+
+// loggingLevels maps each level to its severity.
+var loggingLevels = map[LoggingLevel]int{
+	LevelDebug: 0, LevelInfo: 1, LevelNotice: 2, LevelWarning: 3,
+	LevelError: 4, LevelCritical: 5, LevelAlert: 6, LevelEmergency: 7,
+}
+
+// Valid reports whether l is a known logging level.
+func (l LoggingLevel) Valid() bool {
+	_, ok := loggingLevels[l]
+	return ok
+}
+
+// AtLeast reports whether l is as severe as min.
+func (l LoggingLevel) AtLeast(min LoggingLevel) bool {
+	return loggingLevels[l] >= loggingLevels[min]
+}
+
+// SetLevelParams are the parameters of logging/setLevel.
+type SetLevelParams struct {
+	Level LoggingLevel `json:"level"`
+}
+
+// LoggingMessageParams are the parameters of notifications/message.
+type LoggingMessageParams struct {
+	Level  LoggingLevel `json:"level"`
+	Logger string       `json:"logger,omitempty"`
+	Data   interface{}  `json:"data"`
+}
+
+// Completion reference types.
+const (
+	RefPrompt   = "ref/prompt"
+	RefResource = "ref/resource"
+)
+
+// CompletionRef identifies the prompt or resource template whose argument is completed.
+type CompletionRef struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"` // for ref/prompt
+	URI  string `json:"uri,omitempty"`  // URI template, for ref/resource
+}
+
+// Key returns a string that uniquely identifies the reference.
+func (r CompletionRef) Key() string {
+	if r.Type == RefResource {
+		return r.Type + " " + r.URI
+	}
+	return r.Type + " " + r.Name
+}
+
+// CompleteArgument is the argument being completed and its partial value.
+type CompleteArgument struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CompleteParams are the parameters of completion/complete.
+type CompleteParams struct {
+	Ref      CompletionRef    `json:"ref"`
+	Argument CompleteArgument `json:"argument"`
+}
+
+// Completion holds the suggested values for an argument.
+type Completion struct {
+	Values  []string `json:"values"`
+	Total   int      `json:"total,omitempty"`
+	HasMore bool     `json:"hasMore,omitempty"`
+}
+
+// CompleteResult is the reply to completion/complete.
+type CompleteResult struct {
+	Completion Completion `json:"completion"`
+}
+
+// SamplingMessage is a message of a sampling/createMessage conversation.
+type SamplingMessage struct {
+	Role    string  `json:"role"`
+	Content Content `json:"content"`
+}
+
+// ModelHint names a model the server would prefer the client to use.
+type ModelHint struct {
+	Name string `json:"name,omitempty"`
+}
+
+// ModelPreferences guide the client's choice of model.
+type ModelPreferences struct {
+	Hints                []ModelHint `json:"hints,omitempty"`
+	CostPriority         float64     `json:"costPriority,omitempty"`
+	SpeedPriority        float64     `json:"speedPriority,omitempty"`
+	IntelligencePriority float64     `json:"intelligencePriority,omitempty"`
+}
+
+// CreateMessageParams are the parameters of sampling/createMessage, sent by the
+// server to the client.
+type CreateMessageParams struct {
+	Messages         []SamplingMessage `json:"messages"`
+	ModelPreferences *ModelPreferences `json:"modelPreferences,omitempty"`
+	SystemPrompt     string            `json:"systemPrompt,omitempty"`
+	Temperature      *float64          `json:"temperature,omitempty"`
+	MaxTokens        int               `json:"maxTokens"`
+	StopSequences    []string          `json:"stopSequences,omitempty"`
+}
+
+// CreateMessageResult is the client's reply to sampling/createMessage.
+type CreateMessageResult struct {
+	Role       string  `json:"role"`
+	Content    Content `json:"content"`
+	Model      string  `json:"model"`
+	StopReason string  `json:"stopReason,omitempty"`
+}
+
+// Root is a filesystem location exposed by the client, identified by a file:// URI.
+type Root struct {
+	URI  string `json:"uri"`
+	Name string `json:"name,omitempty"`
+}
+
+// ListRootsResult is the client's reply to roots/list.
+type ListRootsResult struct {
+	Roots []Root `json:"roots"`
+}
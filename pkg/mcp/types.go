@@ -2,28 +2,149 @@
 package mcp
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"text/template"
+	"time"
 
 	"github.com/santoshkal/gomcp/pkg/plugins"
 )
 
 const JSONRPCVersion = "2.0"
 
+// Standard JSON-RPC 2.0 error codes.
+const (
+	ParseError     = -32700
+	InvalidRequest = -32600
+	MethodNotFound = -32601
+	InvalidParams  = -32602
+	InternalError  = -32603
+	// RequestCancelled answers a request that the client cancelled.
+	RequestCancelled = -32800
+	// ServerError is the implementation-defined code used for tool failures.
+	ServerError = -32000
+	// ResourceNotFound answers resources/read for an unknown URI.
+	ResourceNotFound = -32002
+)
+
+// ID is a JSON-RPC request identifier. It keeps the raw string or number token so
+// responses echo it back unchanged, and is empty for notifications.
+type ID json.RawMessage
+
+// MarshalJSON encodes an empty ID as null.
+func (id ID) MarshalJSON() ([]byte, error) {
+	if len(id) == 0 {
+		return []byte("null"), nil
+	}
+	return id, nil
+}
+
+// UnmarshalJSON stores the raw identifier token.
+func (id *ID) UnmarshalJSON(data []byte) error {
+	*id = append((*id)[:0], data...)
+	return nil
+}
+
+// Valid reports whether the ID is a string, a number or null.
+func (id ID) Valid() bool {
+	if len(id) == 0 || string(id) == "null" {
+		return true
+	}
+	switch c := id[0]; {
+	case c == '"':
+		var s string
+		return json.Unmarshal(id, &s) == nil
+	case c == '-' || (c >= '0' && c <= '9'):
+		var n json.Number
+		return json.Unmarshal(id, &n) == nil
+	}
+	return false
+}
+
+// String returns the raw identifier token.
+func (id ID) String() string {
+	return string(id)
+}
+
 // RPCRequest defines the JSON-RPC request structure.
 type RPCRequest struct {
-	Version string        `json:"jsonrpc"`
-	Method  string        `json:"method"`
-	Params  []interface{} `json:"params"`
-	ID      int           `json:"id"`
+	Version string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      ID              `json:"id,omitempty"` // empty for notifications
+}
+
+// IsNotification reports whether the request expects no response.
+func (r *RPCRequest) IsNotification() bool {
+	return len(r.ID) == 0
+}
+
+// Validate checks the request against the JSON-RPC 2.0 specification.
+func (r *RPCRequest) Validate() error {
+	if r.Version != JSONRPCVersion {
+		return fmt.Errorf("jsonrpc must be %q", JSONRPCVersion)
+	}
+	if r.Method == "" {
+		return errors.New("method is required")
+	}
+	if !r.ID.Valid() {
+		return errors.New("id must be a string, number or null")
+	}
+	if params := bytes.TrimSpace(r.Params); len(params) > 0 && string(params) != "null" &&
+		params[0] != '{' && params[0] != '[' {
+		return errors.New("params must be an object or an array")
+	}
+	return nil
+}
+
+// DecodeBatch splits a JSON-RPC payload into its messages. A single message is
+// returned as a one-element slice with batch set to false.
+func DecodeBatch(data []byte) (msgs []json.RawMessage, batch bool, err error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		if err := json.Unmarshal(data, &msgs); err != nil {
+			return nil, true, err
+		}
+		return msgs, true, nil
+	}
+	var msg json.RawMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, false, err
+	}
+	return []json.RawMessage{msg}, false, nil
+}
+
+// NewNotification builds a JSON-RPC notification carrying the given params.
+func NewNotification(method string, params interface{}) (*RPCRequest, error) {
+	req := &RPCRequest{Version: JSONRPCVersion, Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s params: %w", method, err)
+		}
+		req.Params = json.RawMessage(data)
+	}
+	return req, nil
+}
+
+// NewRequest builds a JSON-RPC request with the given ID and params.
+func NewRequest(id ID, method string, params interface{}) (*RPCRequest, error) {
+	req, err := NewNotification(method, params)
+	if err != nil {
+		return nil, err
+	}
+	req.ID = id
+	return req, nil
 }
 
 // RPCResponse defines the JSON-RPC response structure.
 type RPCResponse struct {
 	Version string          `json:"jsonrpc"`
-	Result  json.RawMessage `json:"result"`
-	Error   *RPCError       `json:"error"`
-	ID      *int            `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+	ID      ID              `json:"id"`
 }
 
 // RPCError defines an error in JSON-RPC responses.
@@ -51,7 +172,119 @@ type ToolCallArgs struct {
 	Parameters map[string]interface{} `json:"parameters"`
 }
 
-// Registry defines the interface for registering tools.
+// ServiceInfo describes a service that groups related tools.
+type ServiceInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version,omitempty"`
+	Enabled     bool   `json:"enabled"` // tools of disabled services are not offered
+}
+
+// Registry defines the interface for registering tools and the services they belong to.
 type Registry interface {
 	RegisterTool(name, description string, inputSchema map[string]interface{}, handler plugins.ToolHandler)
+	// DefineService declares a service or updates its metadata and state.
+	DefineService(info ServiceInfo)
+	// RegisterServiceTool registers a tool that belongs to the named service.
+	RegisterServiceTool(service, name, description string, inputSchema map[string]interface{}, handler plugins.ToolHandler)
+}
+
+// ToolRegistration is a tool to be added to, or replaced in, a registry.
+type ToolRegistration struct {
+	Service     string // empty for tools that belong to no service
+	Name        string
+	Description string
+	InputSchema map[string]interface{}
+	Handler     plugins.ToolHandler
+	Limits      ToolLimits
+}
+
+// ToolLimits bound how a tool is invoked. Zero values select the defaults.
+type ToolLimits struct {
+	Timeout        time.Duration // per attempt; the server's tool timeout when zero
+	Retries        int           // attempts repeated after a plugins.Retryable error
+	RetryBackoff   time.Duration // delay before the first retry, doubled for each one
+	MaxConcurrency int           // calls running at once; unlimited when zero
+}
+
+// ResourceRegistration is a resource or URI template to be added to, or
+// replaced in, a registry.
+type ResourceRegistration struct {
+	URI         string
+	Name        string
+	Description string
+	MimeType    string
+	Handler     plugins.ResourceHandler
+	Path        string // local file served instead of calling Handler
+}
+
+// PromptRegistration is a prompt to be added to, or replaced in, a registry.
+type PromptRegistration struct {
+	Name        string
+	Description string
+	Arguments   []PromptArgument
+	Messages    []PromptMessageTemplate
+}
+
+// CompletionRegistration adds completions to a prompt or resource template:
+// either fixed Values for one Argument, or a Completer for all of them.
+type CompletionRegistration struct {
+	Ref       CompletionRef
+	Argument  string
+	Values    []string
+	Completer plugins.Completer
+}
+
+// RegistryUpdate is a set of registry changes applied together. Removals are
+// applied before additions.
+type RegistryUpdate struct {
+	RemoveServices    []string // removed along with their tools
+	RemoveTools       []string
+	RemoveResources   []string // by URI
+	RemovePrompts     []string
+	RemoveCompletions []CompletionRef
+	Services          []ServiceInfo // defined or updated
+	Tools             []ToolRegistration
+	Resources         []ResourceRegistration
+	Prompts           []PromptRegistration
+	Completions       []CompletionRegistration
+}
+
+// UpdatableRegistry is a Registry that applies a RegistryUpdate atomically, so
+// clients never observe a partially applied configuration.
+type UpdatableRegistry interface {
+	Registry
+	ApplyUpdate(update RegistryUpdate)
+}
+
+// ResourceRegistry defines the interface for registering resources. A uri that
+// contains {variables} is registered as a URI template.
+type ResourceRegistry interface {
+	RegisterResource(uri, name, description, mimeType string, handler plugins.ResourceHandler)
+	RegisterFileResource(uri, name, description, mimeType, path string)
+	UnregisterResource(uri string) bool
+}
+
+// PromptMessageTemplate is a prompt message whose text is rendered from the
+// prompt arguments with text/template.
+type PromptMessageTemplate struct {
+	Role     string
+	Template *template.Template
+}
+
+// PromptRegistry defines the interface for registering prompt templates.
+type PromptRegistry interface {
+	RegisterPrompt(name, description string, arguments []PromptArgument, messages []PromptMessageTemplate)
+	UnregisterPrompt(name string) bool
+}
+
+// CompletionRegistry defines the interface for registering argument completions
+// of prompts and resource templates.
+type CompletionRegistry interface {
+	// RegisterCompletionValues offers a fixed set of values for one argument of ref.
+	RegisterCompletionValues(ref CompletionRef, argument string, values []string)
+	// RegisterCompleter computes suggestions for any argument of ref.
+	RegisterCompleter(ref CompletionRef, completer plugins.Completer)
+	// UnregisterCompletions removes all completions of ref.
+	UnregisterCompletions(ref CompletionRef)
 }
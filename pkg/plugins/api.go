@@ -22,6 +22,9 @@ type Tool interface {
 }
 
 // Plugin defines the interface for a plugin that can register multiple tools.
+// A plugin package exports it as a variable named Plugin of any type that
+// implements it, e.g. var Plugin = kit{}; a service that names the package in
+// its plugin field registers all of its tools.
 type Plugin interface {
 	// Tools returns a slice of tools provided by the plugin.
 	Tools() []Tool
@@ -30,9 +33,74 @@ type Plugin interface {
 func HandlerSymbols() map[string]map[string]reflect.Value {
 	return map[string]map[string]reflect.Value{
 		"github.com/santoshkal/gomcp/pkg/plugins/plugins": {
-			"ToolHandler": reflect.ValueOf((*ToolHandler)(nil)),
-			"Tool":        reflect.ValueOf((*Tool)(nil)),
-			"Plugin":      reflect.ValueOf((*Plugin)(nil)),
+			"ToolHandler":     reflect.ValueOf((*ToolHandler)(nil)),
+			"ResourceHandler": reflect.ValueOf((*ResourceHandler)(nil)),
+			"Completer":       reflect.ValueOf((*Completer)(nil)),
+			"Tool":            reflect.ValueOf((*Tool)(nil)),
+			"Plugin":          reflect.ValueOf((*Plugin)(nil)),
+
+			// Wrappers that let interpreted types implement the interfaces.
+			"_Tool":   reflect.ValueOf((*_github_com_santoshkal_gomcp_pkg_plugins_Tool)(nil)),
+			"_Plugin": reflect.ValueOf((*_github_com_santoshkal_gomcp_pkg_plugins_Plugin)(nil)),
+
+			"ProgressReporter":     reflect.ValueOf((*ProgressReporter)(nil)),
+			"ProgressFromContext":  reflect.ValueOf(ProgressFromContext),
+			"WithProgressReporter": reflect.ValueOf(WithProgressReporter),
+
+			"Logger":            reflect.ValueOf((*Logger)(nil)),
+			"LoggerFromContext": reflect.ValueOf(LoggerFromContext),
+			"WithLogger":        reflect.ValueOf(WithLogger),
+
+			"Sampler":                reflect.ValueOf((*Sampler)(nil)),
+			"SamplingMessage":        reflect.ValueOf((*SamplingMessage)(nil)),
+			"SamplingRequest":        reflect.ValueOf((*SamplingRequest)(nil)),
+			"SamplingResult":         reflect.ValueOf((*SamplingResult)(nil)),
+			"SamplerFromContext":     reflect.ValueOf(SamplerFromContext),
+			"WithSampler":            reflect.ValueOf(WithSampler),
+			"ErrSamplingUnsupported": reflect.ValueOf(&ErrSamplingUnsupported).Elem(),
+
+			"Root":             reflect.ValueOf((*Root)(nil)),
+			"RootsFromContext": reflect.ValueOf(RootsFromContext),
+			"WithRoots":        reflect.ValueOf(WithRoots),
+
+			"Secrets":           reflect.ValueOf((*Secrets)(nil)),
+			"SecretFromContext": reflect.ValueOf(SecretFromContext),
+			"WithSecrets":       reflect.ValueOf(WithSecrets),
+
+			"Retryable":   reflect.ValueOf(Retryable),
+			"IsRetryable": reflect.ValueOf(IsRetryable),
 		},
 	}
 }
+
+// _github_com_santoshkal_gomcp_pkg_plugins_Tool is an interface wrapper for Tool type
+type _github_com_santoshkal_gomcp_pkg_plugins_Tool struct {
+	IValue       interface{}
+	WDescription func() string
+	WHandle      func(ctx context.Context, parameters map[string]interface{}) (interface{}, error)
+	WName        func() string
+	WSchema      func() map[string]interface{}
+}
+
+func (W _github_com_santoshkal_gomcp_pkg_plugins_Tool) Description() string {
+	return W.WDescription()
+}
+func (W _github_com_santoshkal_gomcp_pkg_plugins_Tool) Handle(ctx context.Context, parameters map[string]interface{}) (interface{}, error) {
+	return W.WHandle(ctx, parameters)
+}
+func (W _github_com_santoshkal_gomcp_pkg_plugins_Tool) Name() string {
+	return W.WName()
+}
+func (W _github_com_santoshkal_gomcp_pkg_plugins_Tool) Schema() map[string]interface{} {
+	return W.WSchema()
+}
+
+// _github_com_santoshkal_gomcp_pkg_plugins_Plugin is an interface wrapper for Plugin type
+type _github_com_santoshkal_gomcp_pkg_plugins_Plugin struct {
+	IValue interface{}
+	WTools func() []Tool
+}
+
+func (W _github_com_santoshkal_gomcp_pkg_plugins_Plugin) Tools() []Tool {
+	return W.WTools()
+}
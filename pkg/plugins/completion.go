@@ -0,0 +1,7 @@
+package plugins
+
+import "context"
+
+// Completer suggests values for an argument of a prompt or resource template,
+// given the partial value typed so far.
+type Completer func(ctx context.Context, argument, value string) ([]string, error)
@@ -0,0 +1,36 @@
+package plugins
+
+import "context"
+
+// Logger lets a tool handler log messages that reach both the server log and,
+// when the client enabled MCP logging, the client itself.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// loggerKey is the context key for the Logger of a tool call.
+type loggerKey struct{}
+
+// noopLogger discards messages when no logger was attached to the context.
+type noopLogger struct{}
+
+func (noopLogger) Debugf(format string, args ...interface{}) {}
+func (noopLogger) Infof(format string, args ...interface{})  {}
+func (noopLogger) Warnf(format string, args ...interface{})  {}
+func (noopLogger) Errorf(format string, args ...interface{}) {}
+
+// WithLogger returns a context that carries logger.
+func WithLogger(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// LoggerFromContext returns the logger for the current call. It never returns nil.
+func LoggerFromContext(ctx context.Context) Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(Logger); ok && logger != nil {
+		return logger
+	}
+	return noopLogger{}
+}
@@ -0,0 +1,32 @@
+package plugins
+
+import "context"
+
+// ProgressReporter lets a tool handler report the progress of a long-running call.
+type ProgressReporter interface {
+	// Report sends the current progress towards total, which is 0 when unknown.
+	// Progress must increase with every call.
+	Report(progress, total float64, message string) error
+}
+
+// progressKey is the context key for the ProgressReporter of a tool call.
+type progressKey struct{}
+
+// noopProgress discards progress when the caller did not ask for it.
+type noopProgress struct{}
+
+func (noopProgress) Report(progress, total float64, message string) error { return nil }
+
+// WithProgressReporter returns a context that carries reporter.
+func WithProgressReporter(ctx context.Context, reporter ProgressReporter) context.Context {
+	return context.WithValue(ctx, progressKey{}, reporter)
+}
+
+// ProgressFromContext returns the reporter for the current tool call. It never
+// returns nil: when the caller did not request progress, reports are discarded.
+func ProgressFromContext(ctx context.Context) ProgressReporter {
+	if reporter, ok := ctx.Value(progressKey{}).(ProgressReporter); ok && reporter != nil {
+		return reporter
+	}
+	return noopProgress{}
+}
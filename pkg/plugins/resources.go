@@ -0,0 +1,8 @@
+package plugins
+
+import "context"
+
+// ResourceHandler reads the resource at uri. For resources registered from a URI
+// template, vars holds the values extracted from the template's variables.
+// Strings and byte slices are returned as-is; any other value is encoded as JSON.
+type ResourceHandler func(ctx context.Context, uri string, vars map[string]string) (interface{}, error)
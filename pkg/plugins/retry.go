@@ -0,0 +1,27 @@
+package plugins
+
+import "errors"
+
+// retryableError marks an error after which a tool call may safely run again.
+type retryableError struct {
+	err error
+}
+
+func (e retryableError) Error() string { return e.err.Error() }
+
+func (e retryableError) Unwrap() error { return e.err }
+
+// Retryable marks err as safe to retry: the call failed without effect, so a
+// tool configured with retries runs again. Other errors are returned at once.
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return retryableError{err: err}
+}
+
+// IsRetryable reports whether err, or an error it wraps, was marked with Retryable.
+func IsRetryable(err error) bool {
+	var r retryableError
+	return errors.As(err, &r)
+}
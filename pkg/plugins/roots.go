@@ -0,0 +1,25 @@
+package plugins
+
+import "context"
+
+// Root is a filesystem location, given as a file:// URI, that the client allows
+// the server to operate on.
+type Root struct {
+	URI  string
+	Name string
+}
+
+// rootsKey is the context key for the roots of a call.
+type rootsKey struct{}
+
+// WithRoots returns a context that carries the client's roots.
+func WithRoots(ctx context.Context, roots []Root) context.Context {
+	return context.WithValue(ctx, rootsKey{}, roots)
+}
+
+// RootsFromContext returns the roots declared by the client. ok is false when
+// the client did not declare roots, in which case paths are not restricted.
+func RootsFromContext(ctx context.Context) (roots []Root, ok bool) {
+	roots, ok = ctx.Value(rootsKey{}).([]Root)
+	return roots, ok
+}
@@ -0,0 +1,66 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrSamplingUnsupported is returned by a Sampler when the connected client
+// cannot sample from its model.
+var ErrSamplingUnsupported = errors.New("client does not support sampling")
+
+// SamplingMessage is a single text message of a sampling conversation.
+type SamplingMessage struct {
+	Role string // user or assistant
+	Text string
+}
+
+// SamplingRequest asks the client's model for a completion.
+type SamplingRequest struct {
+	Messages     []SamplingMessage
+	SystemPrompt string
+	// MaxTokens bounds the length of the completion. A default is used when 0.
+	MaxTokens     int
+	Temperature   float64 // left to the client when 0
+	StopSequences []string
+	// ModelHints name preferred models, e.g. "claude-3-sonnet"; the client decides.
+	ModelHints []string
+}
+
+// SamplingResult is the completion produced by the client's model.
+type SamplingResult struct {
+	Role       string
+	Text       string
+	Model      string
+	StopReason string
+}
+
+// Sampler lets a handler request a completion from the model of the connected
+// client through MCP sampling. The client may ask its user to approve the request.
+type Sampler interface {
+	CreateMessage(ctx context.Context, req SamplingRequest) (SamplingResult, error)
+}
+
+// samplerKey is the context key for the Sampler of a call.
+type samplerKey struct{}
+
+// noopSampler is used when the call is not tied to a client.
+type noopSampler struct{}
+
+func (noopSampler) CreateMessage(ctx context.Context, req SamplingRequest) (SamplingResult, error) {
+	return SamplingResult{}, ErrSamplingUnsupported
+}
+
+// WithSampler returns a context that carries sampler.
+func WithSampler(ctx context.Context, sampler Sampler) context.Context {
+	return context.WithValue(ctx, samplerKey{}, sampler)
+}
+
+// SamplerFromContext returns the sampler for the current call. It never returns
+// nil: without a client, requests fail with ErrSamplingUnsupported.
+func SamplerFromContext(ctx context.Context) Sampler {
+	if sampler, ok := ctx.Value(samplerKey{}).(Sampler); ok && sampler != nil {
+		return sampler
+	}
+	return noopSampler{}
+}
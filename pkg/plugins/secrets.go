@@ -0,0 +1,23 @@
+package plugins
+
+import "context"
+
+// Secrets are the secret values granted to a tool by the `secrets` of its
+// configuration, keyed by secret name.
+type Secrets map[string]string
+
+// secretsKey is the context key for the secrets of a call.
+type secretsKey struct{}
+
+// WithSecrets returns a context that carries the secrets granted to a tool.
+func WithSecrets(ctx context.Context, secrets Secrets) context.Context {
+	return context.WithValue(ctx, secretsKey{}, secrets)
+}
+
+// SecretFromContext returns the named secret granted to the running tool. ok is
+// false when the tool was not granted the secret.
+func SecretFromContext(ctx context.Context, name string) (value string, ok bool) {
+	secrets, _ := ctx.Value(secretsKey{}).(Secrets)
+	value, ok = secrets[name]
+	return value, ok
+}
@@ -0,0 +1,150 @@
+package reg
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+
+	"github.com/santoshkal/gomcp/pkg/mcp"
+)
+
+// templateVariable matches the {var} and {+var} expressions of a URI template.
+var templateVariable = regexp.MustCompile(`\{\+?([^}]+)\}`)
+
+// completionSource holds the completions offered for the arguments of a
+// prompt or resource template.
+type completionSource struct {
+	ref       mcp.CompletionRef
+	values    map[string][]string // fixed values by argument name
+	completer string              // package exporting a Complete function
+	owner     string              // prompt or resource named in errors
+}
+
+// serviceCompletions returns the completion sources of the enabled prompts and
+// resource templates of an enabled service, keyed by mcp.CompletionRef.Key.
+// Values come from enum entries in the YAML schemas, falling back to same-named
+// properties of the service's tool schemas, and from optional plugin completers.
+func serviceCompletions(svc ServiceConfig) map[string]completionSource {
+	sources := make(map[string]completionSource)
+	if !svc.Enabled {
+		return sources
+	}
+	toolEnums := serviceToolEnums(svc)
+	add := func(src completionSource) {
+		if len(src.values) > 0 || src.completer != "" {
+			sources[src.ref.Key()] = src
+		}
+	}
+
+	for _, p := range svc.Prompts {
+		if !p.Enabled {
+			continue
+		}
+		src := completionSource{
+			ref:       mcp.CompletionRef{Type: mcp.RefPrompt, Name: p.Name},
+			values:    make(map[string][]string),
+			completer: p.Completer,
+			owner:     "prompt " + p.Name,
+		}
+		for _, arg := range p.Arguments {
+			values := schemaEnum(arg.Schema)
+			if len(values) == 0 {
+				values = toolEnums[arg.Name]
+			}
+			if len(values) > 0 {
+				src.values[arg.Name] = values
+			}
+		}
+		add(src)
+	}
+
+	for _, res := range svc.Resources {
+		if !res.Enabled || !templateVariable.MatchString(res.URI) {
+			continue
+		}
+		src := completionSource{
+			ref:       mcp.CompletionRef{Type: mcp.RefResource, URI: res.URI},
+			values:    make(map[string][]string),
+			completer: res.Completer,
+			owner:     "resource " + res.Name,
+		}
+		properties, _ := normalizeYAML(res.Schema["properties"]).(map[string]interface{})
+		for _, match := range templateVariable.FindAllStringSubmatch(res.URI, -1) {
+			name := match[1]
+			property, _ := properties[name].(map[string]interface{})
+			values := schemaEnum(property)
+			if len(values) == 0 {
+				values = toolEnums[name]
+			}
+			if len(values) > 0 {
+				src.values[name] = values
+			}
+		}
+		add(src)
+	}
+	return sources
+}
+
+// registerCompletions registers the argument completions of the enabled
+// prompts and resource templates of a service.
+func registerCompletions(r mcp.CompletionRegistry, svc ServiceConfig) error {
+	sources := serviceCompletions(svc)
+	for _, key := range slices.Sorted(maps.Keys(sources)) {
+		src := sources[key]
+		for _, name := range slices.Sorted(maps.Keys(src.values)) {
+			r.RegisterCompletionValues(src.ref, name, src.values[name])
+		}
+		if err := registerCompleter(r, src.ref, src.completer); err != nil {
+			return fmt.Errorf("%s: %v", src.owner, err)
+		}
+	}
+	return nil
+}
+
+// registerCompleter loads the Complete function of a plugin, if one is configured.
+func registerCompleter(r mcp.CompletionRegistry, ref mcp.CompletionRef, plugin string) error {
+	if plugin == "" {
+		return nil
+	}
+	v, err := loadPluginSymbol(plugin, "Complete")
+	if err != nil {
+		return fmt.Errorf("failed to load Complete: %v", err)
+	}
+	completer, ok := v.Interface().(func(context.Context, string, string) ([]string, error))
+	if !ok {
+		return fmt.Errorf("Complete in %s does not have the correct signature", plugin)
+	}
+	r.RegisterCompleter(ref, completer)
+	return nil
+}
+
+// serviceToolEnums collects the enum values of the properties declared by the
+// enabled tools of a service, keyed by property name.
+func serviceToolEnums(svc ServiceConfig) map[string][]string {
+	enums := make(map[string][]string)
+	for _, tool := range svc.Tools {
+		if !tool.Enabled {
+			continue
+		}
+		properties, _ := normalizeYAML(tool.Schema["properties"]).(map[string]interface{})
+		for name, property := range properties {
+			if values := schemaEnum(property); len(values) > 0 && enums[name] == nil {
+				enums[name] = values
+			}
+		}
+	}
+	return enums
+}
+
+// schemaEnum returns the enum values of a JSON schema as strings.
+func schemaEnum(schema interface{}) []string {
+	m, _ := normalizeYAML(schema).(map[string]interface{})
+	items, _ := m["enum"].([]interface{})
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		values = append(values, fmt.Sprint(item))
+	}
+	return values
+}
@@ -0,0 +1,245 @@
+package reg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFile is one file of a configuration, parsed and decoded.
+type configFile struct {
+	path string
+	root *yaml.Node // top-level mapping, nil for an empty file
+	cfg  Config
+}
+
+// configReader reads the files of a configuration, following includes.
+type configReader struct {
+	*validator
+	files []*configFile
+	dirs  []string        // directories read or searched by include patterns
+	seen  map[string]bool // absolute paths of the files read
+	stack []string        // files being read, to detect include cycles
+}
+
+// loadConfig reads, merges and validates the configuration at path, which is
+// a YAML file or a directory of them. Problems are reported as ConfigErrors
+// with the file and line they were found at.
+//
+// A directory contributes its *.yaml and *.yml files in name order. The
+// include entries of a file name files, directories or glob patterns, relative
+// to the file, that are read before it; each file is read once. Services,
+// tools, resources, prompts and secrets may each be defined in one place only,
+// and a second definition is reported as a conflict. Server settings are
+// merged, with the settings of later files overriding earlier ones, so a file
+// takes precedence over the files it includes.
+func loadConfig(path string) (*Config, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("failed to read YAML config: %w", err)
+	}
+	cr := &configReader{validator: newValidator(), seen: make(map[string]bool)}
+	cr.read(path, nil)
+
+	// Secrets are read from every file first, as tools may use secrets
+	// defined in another file.
+	for _, f := range cr.files {
+		cr.file = f.path
+		cr.secretsSection(field(f.root, "secrets"))
+	}
+	cfg := &Config{}
+	for _, f := range cr.files {
+		cr.file = f.path
+		cr.config(f.root)
+		f.resolvePaths()
+		cfg.merge(&f.cfg)
+		cfg.files = append(cfg.files, f.path)
+	}
+	cfg.files = append(cfg.files, cr.dirs...)
+	if err := cr.result(); err != nil {
+		return nil, err
+	}
+	cfg.secrets = cr.secrets
+	return cfg, nil
+}
+
+// read reads the file or directory at path. from is the include entry that
+// named it, or nil for the configuration path itself.
+func (cr *configReader) read(path string, from *yaml.Node) {
+	info, err := os.Stat(path)
+	if err != nil {
+		var pathErr *os.PathError
+		if errors.As(err, &pathErr) {
+			err = pathErr.Err
+		}
+		cr.errorf(from, "include %s: %v", path, err)
+		return
+	}
+	if !info.IsDir() {
+		cr.readFile(path, from)
+		return
+	}
+
+	cr.dirs = append(cr.dirs, path)
+	var files []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, _ := filepath.Glob(filepath.Join(path, pattern))
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		cr.readFile(file, from)
+	}
+}
+
+// readFile parses a configuration file and reads its includes before adding
+// it to the files of the configuration.
+func (cr *configReader) readFile(path string, from *yaml.Node) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	for i, file := range cr.stack {
+		if file == abs {
+			cr.errorf(from, "include cycle: %s", strings.Join(append(cr.stack[i:], abs), " -> "))
+			return
+		}
+	}
+	if cr.seen[abs] {
+		return
+	}
+	cr.seen[abs] = true
+
+	including := cr.file
+	defer func() { cr.file = including }()
+	cr.file = path
+
+	f, ok := cr.parse(path)
+	if !ok {
+		return
+	}
+
+	cr.stack = append(cr.stack, abs)
+	for _, entry := range items(field(f.root, "include")) {
+		cr.include(filepath.Dir(path), entry)
+	}
+	cr.stack = cr.stack[:len(cr.stack)-1]
+	cr.files = append(cr.files, f)
+}
+
+// include reads the files named by an include entry relative to dir.
+func (cr *configReader) include(dir string, entry *yaml.Node) {
+	pattern := entry.Value
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		cr.errorf(entry, "include %s: %v", entry.Value, err)
+		return
+	}
+	if strings.ContainsAny(entry.Value, "*?[") {
+		cr.dirs = append(cr.dirs, filepath.Dir(pattern))
+	} else if len(matches) == 0 {
+		matches = []string{pattern} // reported as missing by read
+	}
+	for _, match := range matches {
+		cr.read(match, entry)
+	}
+}
+
+// resolvePaths makes the relative paths of file resources relative to the
+// directory of the file, like those of secrets and includes.
+func (f *configFile) resolvePaths() {
+	for i := range f.cfg.Services {
+		resources := f.cfg.Services[i].Resources
+		for j := range resources {
+			if path := resources[j].Path; path != "" && !filepath.IsAbs(path) {
+				resources[j].Path = filepath.Join(filepath.Dir(f.path), path)
+			}
+		}
+	}
+}
+
+// parse reads a configuration file, interpolates environment variables and
+// decodes it. It returns false when the file could not be parsed.
+func (cr *configReader) parse(path string) (*configFile, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		cr.errs = append(cr.errs, &ConfigError{File: path, Message: err.Error()})
+		return nil, false
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		cr.errs = append(cr.errs, yamlError(path, strings.TrimPrefix(err.Error(), "yaml: ")))
+		return nil, false
+	}
+	f := &configFile{path: path}
+	if len(doc.Content) == 0 {
+		return f, true
+	}
+	f.root = doc.Content[0]
+
+	cr.interpolate(f.root)
+	cr.unknownFields(f.root, reflect.TypeOf(Config{}))
+	if err := f.root.Decode(&f.cfg); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			cr.errs = append(cr.errs, yamlError(path, err.Error()))
+			return nil, false
+		}
+		for _, msg := range typeErr.Errors {
+			cr.errs = append(cr.errs, yamlError(path, msg))
+		}
+	}
+	return f, true
+}
+
+// merge adds the services and secrets of src to c. Server settings set in src
+// override those of c.
+func (c *Config) merge(src *Config) {
+	c.Services = append(c.Services, src.Services...)
+	for name, secret := range src.Secrets {
+		if c.Secrets == nil {
+			c.Secrets = make(map[string]SecretConfig)
+		}
+		c.Secrets[name] = secret
+	}
+
+	s, d := src.Server, &c.Server
+	if s.Address != "" {
+		d.Address = s.Address
+	}
+	if s.Transport != "" {
+		d.Transport = s.Transport
+	}
+	if s.LogLevel != "" {
+		d.LogLevel = s.LogLevel
+	}
+	if s.LLM.Provider != "" {
+		d.LLM.Provider = s.LLM.Provider
+	}
+	if s.LLM.Model != "" {
+		d.LLM.Model = s.LLM.Model
+	}
+	if s.Timeouts.Tool != 0 {
+		d.Timeouts.Tool = s.Timeouts.Tool
+	}
+	if s.Timeouts.Resource != 0 {
+		d.Timeouts.Resource = s.Timeouts.Resource
+	}
+	if s.Timeouts.Completion != 0 {
+		d.Timeouts.Completion = s.Timeouts.Completion
+	}
+	if s.Timeouts.Session != 0 {
+		d.Timeouts.Session = s.Timeouts.Session
+	}
+	if len(s.AllowedOrigins) > 0 {
+		d.AllowedOrigins = s.AllowedOrigins
+	}
+}
@@ -0,0 +1,253 @@
+package reg
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeTree writes the configuration files of a test, keyed by path relative
+// to dir.
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		writeConfig(t, dir, name, content)
+	}
+}
+
+func TestLoadConfigIncludes(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        map[string]string
+		load         string // path to load, relative to the tree
+		wantServices []string
+		wantServer   ServerConfig
+		wantFiles    []string
+	}{
+		{
+			name: "included files are read first",
+			files: map[string]string{
+				"gomcp.yaml":  "include: [docker.yaml]\nservices:\n  - name: Git\n",
+				"docker.yaml": "services:\n  - name: Docker\n",
+			},
+			load:         "gomcp.yaml",
+			wantServices: []string{"Docker", "Git"},
+			wantFiles:    []string{"docker.yaml", "gomcp.yaml"},
+		},
+		{
+			name: "glob include",
+			files: map[string]string{
+				"gomcp.yaml":       "include: [\"conf.d/*.yaml\"]\nservices:\n  - name: Git\n",
+				"conf.d/20-b.yaml": "services:\n  - name: Podman\n",
+				"conf.d/10-a.yaml": "services:\n  - name: Docker\n",
+				"conf.d/notes.txt": "not configuration",
+			},
+			load:         "gomcp.yaml",
+			wantServices: []string{"Docker", "Podman", "Git"},
+			wantFiles:    []string{"conf.d/10-a.yaml", "conf.d/20-b.yaml", "gomcp.yaml", "conf.d"},
+		},
+		{
+			name: "directory in name order",
+			files: map[string]string{
+				"conf/b.yml":  "services:\n  - name: Podman\nserver:\n  log_level: info\n",
+				"conf/a.yaml": "services:\n  - name: Docker\nserver:\n  address: \":1234\"\n  log_level: debug\n",
+				"conf/c.yaml": "services:\n  - name: Git\nserver:\n  address: \":5678\"\n",
+				"conf/README": "services: not read",
+			},
+			load:         "conf",
+			wantServices: []string{"Docker", "Podman", "Git"},
+			wantServer:   ServerConfig{Address: ":5678", LogLevel: "info"},
+			wantFiles:    []string{"conf/a.yaml", "conf/b.yml", "conf/c.yaml", "conf"},
+		},
+		{
+			name: "including file takes precedence",
+			files: map[string]string{
+				"gomcp.yaml": "include: [base.yaml]\nserver:\n  address: \":9000\"\n  timeouts:\n    tool: 5s\n",
+				"base.yaml":  "server:\n  address: \":1234\"\n  transport: stdio\n  allowed_origins: [\"https://app.example.com\"]\n  timeouts:\n    tool: 1m\n    resource: 10s\n",
+			},
+			load: "gomcp.yaml",
+			wantServer: ServerConfig{
+				Address:        ":9000",
+				Transport:      "stdio",
+				Timeouts:       TimeoutConfig{Tool: 5e9, Resource: 10e9},
+				AllowedOrigins: []string{"https://app.example.com"},
+			},
+			wantFiles: []string{"base.yaml", "gomcp.yaml"},
+		},
+		{
+			name: "shared include is read once",
+			files: map[string]string{
+				"gomcp.yaml":  "include: [docker.yaml, git.yaml]\n",
+				"docker.yaml": "include: [common.yaml]\nservices:\n  - name: Docker\n",
+				"git.yaml":    "include: [common.yaml]\nservices:\n  - name: Git\n",
+				"common.yaml": "secrets:\n  token:\n    value: abc\nservices:\n  - name: Common\n",
+			},
+			load:         "gomcp.yaml",
+			wantServices: []string{"Common", "Docker", "Git"},
+			wantFiles:    []string{"common.yaml", "docker.yaml", "git.yaml", "gomcp.yaml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTree(t, dir, tt.files)
+			cfg, err := loadConfig(filepath.Join(dir, tt.load))
+			if err != nil {
+				t.Fatalf("loadConfig: %v", err)
+			}
+
+			var services []string
+			for _, svc := range cfg.Services {
+				services = append(services, svc.Name)
+			}
+			if !reflect.DeepEqual(services, tt.wantServices) {
+				t.Errorf("services = %q, want %q", services, tt.wantServices)
+			}
+			if !reflect.DeepEqual(cfg.Server, tt.wantServer) {
+				t.Errorf("server = %+v, want %+v", cfg.Server, tt.wantServer)
+			}
+			var files []string
+			for _, file := range cfg.files {
+				rel, err := filepath.Rel(dir, file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				files = append(files, filepath.ToSlash(rel))
+			}
+			if !reflect.DeepEqual(files, tt.wantFiles) {
+				t.Errorf("files = %q, want %q", files, tt.wantFiles)
+			}
+		})
+	}
+}
+
+func TestLoadConfigIncludeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		load  string
+		want  []string
+	}{
+		{
+			name: "cycle",
+			files: map[string]string{
+				"gomcp.yaml":  "include: [docker.yaml]\n",
+				"docker.yaml": "include:\n  - gomcp.yaml\n",
+			},
+			load: "gomcp.yaml",
+			want: []string{
+				"docker.yaml:2: include cycle: gomcp.yaml -> docker.yaml -> gomcp.yaml",
+			},
+		},
+		{
+			name: "self include",
+			files: map[string]string{
+				"gomcp.yaml": "include: [\"*.yaml\"]\n",
+			},
+			load: "gomcp.yaml",
+			want: []string{
+				"gomcp.yaml:1: include cycle: gomcp.yaml -> gomcp.yaml",
+			},
+		},
+		{
+			name: "missing include",
+			files: map[string]string{
+				"gomcp.yaml": "include:\n  - conf.d\n  - missing.yaml\n",
+			},
+			load: "gomcp.yaml",
+			want: []string{
+				"gomcp.yaml:2: include conf.d: no such file or directory",
+				"gomcp.yaml:3: include missing.yaml: no such file or directory",
+			},
+		},
+		{
+			name: "conflicts across files",
+			files: map[string]string{
+				"gomcp.yaml": `include: [conf.d]
+secrets:
+  token:
+    value: abc
+services:
+  - name: Docker
+    tools:
+      - name: create_network
+        plugin: example.com/plug
+    prompts:
+      - name: create_app_network
+        template: hi
+`,
+				"conf.d/docker.yaml": `secrets:
+  token:
+    env: HOME
+services:
+  - name: Docker
+  - name: Podman
+    tools:
+      - name: create_network
+        plugin: example.com/pod
+    prompts:
+      - name: create_app_network
+        template: hi
+`,
+			},
+			load: "gomcp.yaml",
+			want: []string{
+				"gomcp.yaml:3: duplicate secret token, first defined at conf.d/docker.yaml:2",
+				"gomcp.yaml:6: duplicate service Docker, first defined at conf.d/docker.yaml:5",
+				"gomcp.yaml:8: duplicate tool create_network, first defined at conf.d/docker.yaml:8",
+				"gomcp.yaml:11: duplicate prompt create_app_network, first defined at conf.d/docker.yaml:11",
+			},
+		},
+		{
+			name: "errors in included files",
+			files: map[string]string{
+				"gomcp.yaml":  "include: [docker.yaml]\nserver:\n  transport: grpc\n",
+				"docker.yaml": "services:\n  - name: Docker\n    tols: []\n",
+			},
+			load: "gomcp.yaml",
+			want: []string{
+				`docker.yaml:3: unknown field "tols"`,
+				`gomcp.yaml:3: server.transport "grpc" is invalid: expected http or stdio`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTree(t, dir, tt.files)
+			if got := configErrors(t, dir, filepath.Join(dir, tt.load)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadConfig errors:\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigResourcePaths(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"gomcp.yaml": "include: [conf.d]\n",
+		"conf.d/docker.yaml": `services:
+  - name: Docker
+    resources:
+      - uri: file:///motd
+        name: motd
+        path: motd.txt
+      - uri: file:///hosts
+        name: hosts
+        path: /etc/hosts
+`,
+	})
+	cfg, err := loadConfig(filepath.Join(dir, "gomcp.yaml"))
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	var paths []string
+	for _, res := range cfg.Services[0].Resources {
+		paths = append(paths, res.Path)
+	}
+	if want := []string{filepath.Join(dir, "conf.d", "motd.txt"), "/etc/hosts"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("resource paths = %q, want %q", paths, want)
+	}
+}
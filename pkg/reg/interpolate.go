@@ -0,0 +1,60 @@
+package reg
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// interpolationPattern matches $$, ${VAR}, ${VAR:-default} and unterminated
+// or malformed ${ references, which are reported as errors.
+var interpolationPattern = regexp.MustCompile(`\$\$|\$\{[^}]*\}?`)
+
+// variablePattern matches the inside of a well-formed reference.
+var variablePattern = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)(:-(.*))?$`)
+
+// interpolate replaces environment variable references in the scalar values
+// of n. ${VAR} is replaced by the value of VAR, which must be set, and
+// ${VAR:-default} by the value of VAR or default when VAR is unset or empty.
+// $$ stands for a literal $. Mapping keys are left untouched.
+func (v *validator) interpolate(n *yaml.Node) {
+	switch n.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, item := range n.Content {
+			v.interpolate(item)
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			v.interpolate(n.Content[i])
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(n.Value, "$") {
+			return
+		}
+		n.Value = interpolationPattern.ReplaceAllStringFunc(n.Value, func(ref string) string {
+			if ref == "$$" {
+				return "$"
+			}
+			m := variablePattern.FindStringSubmatch(strings.TrimSuffix(strings.TrimPrefix(ref, "${"), "}"))
+			if m == nil || !strings.HasSuffix(ref, "}") {
+				v.errorf(n, "invalid variable reference %s: expected ${VAR} or ${VAR:-default}", ref)
+				return ref
+			}
+			value, set := os.LookupEnv(m[1])
+			switch {
+			case m[2] != "" && value == "":
+				return m[3]
+			case !set:
+				v.errorf(n, "environment variable %s is not set", m[1])
+			}
+			return value
+		})
+		// Resolve the type of plain values again, so that e.g. ${ENABLED}
+		// can stand for a boolean. Explicit tags such as !!str are kept.
+		if n.Style&yaml.TaggedStyle == 0 {
+			n.Tag = ""
+		}
+	}
+}
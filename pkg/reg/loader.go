@@ -0,0 +1,558 @@
+package reg
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/santoshkal/gomcp/pkg/mcp"
+	"github.com/santoshkal/gomcp/pkg/plugins"
+)
+
+// Loader applies a configuration file to a registry and reapplies it when the
+// file changes. Each reload only touches the services and tools that changed.
+type Loader struct {
+	path string
+
+	mu      sync.Mutex
+	current *Config // last applied configuration
+}
+
+// ReloadResult summarizes the changes applied by a reload.
+type ReloadResult struct {
+	AddedServices      []string `json:"addedServices,omitempty"`
+	UpdatedServices    []string `json:"updatedServices,omitempty"`
+	RemovedServices    []string `json:"removedServices,omitempty"`
+	AddedTools         []string `json:"addedTools,omitempty"`
+	UpdatedTools       []string `json:"updatedTools,omitempty"`
+	RemovedTools       []string `json:"removedTools,omitempty"`
+	AddedResources     []string `json:"addedResources,omitempty"` // by URI
+	UpdatedResources   []string `json:"updatedResources,omitempty"`
+	RemovedResources   []string `json:"removedResources,omitempty"`
+	AddedPrompts       []string `json:"addedPrompts,omitempty"`
+	UpdatedPrompts     []string `json:"updatedPrompts,omitempty"`
+	RemovedPrompts     []string `json:"removedPrompts,omitempty"`
+	AddedCompletions   []string `json:"addedCompletions,omitempty"` // by reference, e.g. "ref/prompt greet"
+	UpdatedCompletions []string `json:"updatedCompletions,omitempty"`
+	RemovedCompletions []string `json:"removedCompletions,omitempty"`
+}
+
+// Empty reports whether the reload changed nothing.
+func (r *ReloadResult) Empty() bool {
+	return len(r.AddedServices)+len(r.UpdatedServices)+len(r.RemovedServices)+
+		len(r.AddedTools)+len(r.UpdatedTools)+len(r.RemovedTools)+
+		len(r.AddedResources)+len(r.UpdatedResources)+len(r.RemovedResources)+
+		len(r.AddedPrompts)+len(r.UpdatedPrompts)+len(r.RemovedPrompts)+
+		len(r.AddedCompletions)+len(r.UpdatedCompletions)+len(r.RemovedCompletions) == 0
+}
+
+// String lists the changes by kind, e.g. "updated tools [create_network]".
+func (r *ReloadResult) String() string {
+	var parts []string
+	for _, change := range []struct {
+		kind  string
+		names []string
+	}{
+		{"added services", r.AddedServices}, {"updated services", r.UpdatedServices}, {"removed services", r.RemovedServices},
+		{"added tools", r.AddedTools}, {"updated tools", r.UpdatedTools}, {"removed tools", r.RemovedTools},
+		{"added resources", r.AddedResources}, {"updated resources", r.UpdatedResources}, {"removed resources", r.RemovedResources},
+		{"added prompts", r.AddedPrompts}, {"updated prompts", r.UpdatedPrompts}, {"removed prompts", r.RemovedPrompts},
+		{"added completions", r.AddedCompletions}, {"updated completions", r.UpdatedCompletions}, {"removed completions", r.RemovedCompletions},
+	} {
+		if len(change.names) > 0 {
+			parts = append(parts, fmt.Sprintf("%s %v", change.kind, change.names))
+		}
+	}
+	if len(parts) == 0 {
+		return "no changes"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// NewLoader returns a loader for the configuration file at path. Nothing is
+// loaded until Reload is called.
+func NewLoader(path string) *Loader {
+	return &Loader{path: path, current: &Config{}}
+}
+
+// Path returns the configuration file the loader reads.
+func (l *Loader) Path() string {
+	return l.path
+}
+
+// Files returns the files and directories the last applied configuration was
+// read from, so that changes to them can be watched.
+func (l *Loader) Files() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.current.files
+}
+
+// Settings returns the server settings of the last applied configuration.
+func (l *Loader) Settings() ServerConfig {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.current.Server.WithDefaults()
+}
+
+// Reload reads the configuration file and applies the differences from the
+// last applied configuration to r. All plugins of changed tools, resources and
+// completions are loaded before anything is applied, so if one fails to load
+// the registry is left untouched and the error is returned. Secrets are read
+// again on every reload, and tools whose secret values or plugin code changed
+// are replaced.
+func (l *Loader) Reload(r mcp.Registry) (*ReloadResult, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cfg, err := loadConfig(l.path)
+	if err != nil {
+		return nil, err
+	}
+	staged, result, err := stageChanges(l.current, cfg)
+	if err != nil {
+		return nil, err
+	}
+	staged.apply(r)
+	l.current = cfg
+	return result, nil
+}
+
+// stageChanges loads everything that differs between the old and new
+// configuration into a staged registry.
+func stageChanges(old, cfg *Config) (*stagedRegistry, *ReloadResult, error) {
+	if err := expandPluginTools(cfg); err != nil {
+		return nil, nil, err
+	}
+	cfg.stamps = pluginStamps(cfg)
+	oldServices, err := servicesByName(old)
+	if err != nil {
+		return nil, nil, err
+	}
+	newServices, err := servicesByName(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	staged := &stagedRegistry{}
+	result := &ReloadResult{}
+
+	for _, svc := range old.Services {
+		if _, ok := newServices[svc.Name]; !ok {
+			staged.update.RemoveServices = append(staged.update.RemoveServices, svc.Name)
+			result.RemovedServices = append(result.RemovedServices, svc.Name)
+			unregisterServiceExtras(staged, svc)
+			result.diffExtras(old, cfg, svc, ServiceConfig{})
+		}
+	}
+
+	for _, svc := range cfg.Services {
+		prev, existed := oldServices[svc.Name]
+		if existed && reflect.DeepEqual(prev, svc) && !secretsChanged(old, cfg, svc) && !pluginsChanged(old, cfg, svc) {
+			continue
+		}
+		if existed {
+			result.UpdatedServices = append(result.UpdatedServices, svc.Name)
+		} else {
+			result.AddedServices = append(result.AddedServices, svc.Name)
+		}
+		staged.DefineService(mcp.ServiceInfo{
+			Name:        svc.Name,
+			Description: svc.Description,
+			Version:     svc.Version,
+			Enabled:     svc.Enabled,
+		})
+
+		oldTools, newTools := enabledTools(prev), enabledTools(svc)
+		for _, tool := range prev.Tools {
+			if _, ok := oldTools[tool.Name]; !ok {
+				continue
+			}
+			if _, ok := newTools[tool.Name]; !ok {
+				staged.update.RemoveTools = append(staged.update.RemoveTools, tool.Name)
+				result.RemovedTools = append(result.RemovedTools, tool.Name)
+			}
+		}
+		for _, tool := range svc.Tools {
+			if _, ok := newTools[tool.Name]; !ok {
+				continue
+			}
+			prevTool, had := oldTools[tool.Name]
+			if had && reflect.DeepEqual(prevTool, tool) &&
+				reflect.DeepEqual(old.toolSecrets(prevTool), cfg.toolSecrets(tool)) &&
+				old.stamps[tool.Plugin] == cfg.stamps[tool.Plugin] {
+				continue
+			}
+			if err := registerTool(staged, svc.Name, tool, cfg.toolSecrets(tool)); err != nil {
+				return nil, nil, err
+			}
+			if had {
+				result.UpdatedTools = append(result.UpdatedTools, tool.Name)
+			} else {
+				result.AddedTools = append(result.AddedTools, tool.Name)
+			}
+		}
+
+		// Resources, prompts and completions of a changed service are replaced
+		// as a whole.
+		if existed {
+			unregisterServiceExtras(staged, prev)
+		}
+		result.diffExtras(old, cfg, prev, svc)
+		if !svc.Enabled {
+			continue
+		}
+		if err := registerResources(staged, svc.Resources); err != nil {
+			return nil, nil, err
+		}
+		if err := registerPrompts(staged, svc.Prompts); err != nil {
+			return nil, nil, err
+		}
+		if err := registerCompletions(staged, svc); err != nil {
+			return nil, nil, err
+		}
+	}
+	return staged, result, nil
+}
+
+// diffExtras records the resources, prompts and completions that were added,
+// updated or removed between the old and new configuration of a service. An
+// entry is updated when its configuration or the code of its plugin changed.
+func (r *ReloadResult) diffExtras(old, cfg *Config, prev, svc ServiceConfig) {
+	pluginChanged := func(path string) bool {
+		return path != "" && old.stamps[path] != cfg.stamps[path]
+	}
+
+	added, updated, removed := diffNamed(enabledResources(prev), enabledResources(svc), func(a, b ResourceConfig) bool {
+		return !reflect.DeepEqual(a, b) || pluginChanged(b.Plugin)
+	})
+	r.AddedResources = append(r.AddedResources, added...)
+	r.UpdatedResources = append(r.UpdatedResources, updated...)
+	r.RemovedResources = append(r.RemovedResources, removed...)
+
+	added, updated, removed = diffNamed(enabledPrompts(prev), enabledPrompts(svc), func(a, b PromptConfig) bool {
+		return !reflect.DeepEqual(a, b)
+	})
+	r.AddedPrompts = append(r.AddedPrompts, added...)
+	r.UpdatedPrompts = append(r.UpdatedPrompts, updated...)
+	r.RemovedPrompts = append(r.RemovedPrompts, removed...)
+
+	added, updated, removed = diffNamed(serviceCompletions(prev), serviceCompletions(svc), func(a, b completionSource) bool {
+		return !reflect.DeepEqual(a, b) || pluginChanged(b.completer)
+	})
+	r.AddedCompletions = append(r.AddedCompletions, added...)
+	r.UpdatedCompletions = append(r.UpdatedCompletions, updated...)
+	r.RemovedCompletions = append(r.RemovedCompletions, removed...)
+}
+
+// diffNamed returns the sorted names of the entries only in next, in both but
+// changed, and only in prev.
+func diffNamed[T any](prev, next map[string]T, changed func(a, b T) bool) (added, updated, removed []string) {
+	for _, name := range slices.Sorted(maps.Keys(next)) {
+		before, ok := prev[name]
+		switch {
+		case !ok:
+			added = append(added, name)
+		case changed(before, next[name]):
+			updated = append(updated, name)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(prev)) {
+		if _, ok := next[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	return added, updated, removed
+}
+
+// servicesByName indexes the services of cfg, rejecting unnamed and duplicate
+// services and tools defined by more than one service.
+func servicesByName(cfg *Config) (map[string]ServiceConfig, error) {
+	services := make(map[string]ServiceConfig, len(cfg.Services))
+	tools := make(map[string]string) // service of each tool
+	for _, svc := range cfg.Services {
+		if svc.Name == "" {
+			return nil, fmt.Errorf("service without a name")
+		}
+		if _, dup := services[svc.Name]; dup {
+			return nil, fmt.Errorf("duplicate service %s", svc.Name)
+		}
+		services[svc.Name] = svc
+		for _, tool := range svc.Tools {
+			if other, dup := tools[tool.Name]; dup {
+				return nil, fmt.Errorf("tool %s of service %s is already defined by service %s", tool.Name, svc.Name, other)
+			}
+			tools[tool.Name] = svc.Name
+		}
+	}
+	return services, nil
+}
+
+// secretsChanged reports whether the value of a secret granted to a tool of
+// svc differs between the old and new configuration.
+func secretsChanged(old, cfg *Config, svc ServiceConfig) bool {
+	for _, tool := range svc.Tools {
+		if !reflect.DeepEqual(old.toolSecrets(tool), cfg.toolSecrets(tool)) {
+			return true
+		}
+	}
+	return false
+}
+
+// servicePlugins returns the import paths of the plugins svc names.
+func servicePlugins(svc ServiceConfig) []string {
+	paths := []string{svc.Plugin}
+	for _, tool := range svc.Tools {
+		paths = append(paths, tool.Plugin)
+	}
+	for _, res := range svc.Resources {
+		paths = append(paths, res.Plugin, res.Completer)
+	}
+	for _, p := range svc.Prompts {
+		paths = append(paths, p.Completer)
+	}
+	return slices.DeleteFunc(paths, func(path string) bool { return path == "" })
+}
+
+// pluginStamps records the state of the package of every plugin cfg names.
+func pluginStamps(cfg *Config) map[string]string {
+	stamps := make(map[string]string)
+	for _, svc := range cfg.Services {
+		for _, path := range servicePlugins(svc) {
+			if _, ok := stamps[path]; !ok {
+				stamps[path] = pluginStamp(path)
+			}
+		}
+	}
+	return stamps
+}
+
+// pluginsChanged reports whether the code of a plugin named by svc changed
+// between the old and new configuration.
+func pluginsChanged(old, cfg *Config, svc ServiceConfig) bool {
+	for _, path := range servicePlugins(svc) {
+		if old.stamps[path] != cfg.stamps[path] {
+			return true
+		}
+	}
+	return false
+}
+
+// enabledTools returns the tools a service configuration registers, keyed by name.
+func enabledTools(svc ServiceConfig) map[string]ToolConfig {
+	tools := make(map[string]ToolConfig)
+	if !svc.Enabled {
+		return tools
+	}
+	for _, tool := range svc.Tools {
+		if tool.Enabled {
+			tools[tool.Name] = tool
+		}
+	}
+	return tools
+}
+
+// enabledResources returns the resources a service configuration registers,
+// keyed by URI.
+func enabledResources(svc ServiceConfig) map[string]ResourceConfig {
+	resources := make(map[string]ResourceConfig)
+	if !svc.Enabled {
+		return resources
+	}
+	for _, res := range svc.Resources {
+		if res.Enabled {
+			resources[res.URI] = res
+		}
+	}
+	return resources
+}
+
+// enabledPrompts returns the prompts a service configuration registers, keyed
+// by name.
+func enabledPrompts(svc ServiceConfig) map[string]PromptConfig {
+	prompts := make(map[string]PromptConfig)
+	if !svc.Enabled {
+		return prompts
+	}
+	for _, p := range svc.Prompts {
+		if p.Enabled {
+			prompts[p.Name] = p
+		}
+	}
+	return prompts
+}
+
+// unregisterServiceExtras stages the removal of the resources, prompts and
+// completions registered for a service configuration.
+func unregisterServiceExtras(staged *stagedRegistry, svc ServiceConfig) {
+	if !svc.Enabled {
+		return
+	}
+	for _, res := range svc.Resources {
+		if res.Enabled {
+			staged.UnregisterResource(res.URI)
+			staged.UnregisterCompletions(mcp.CompletionRef{Type: mcp.RefResource, URI: res.URI})
+		}
+	}
+	for _, p := range svc.Prompts {
+		if p.Enabled {
+			staged.UnregisterPrompt(p.Name)
+			staged.UnregisterCompletions(mcp.CompletionRef{Type: mcp.RefPrompt, Name: p.Name})
+		}
+	}
+}
+
+// stagedRegistry records registrations so that they can be applied to the real
+// registry only once every plugin has loaded.
+type stagedRegistry struct {
+	update mcp.RegistryUpdate
+}
+
+// Ensure stagedRegistry records every kind of registration.
+var (
+	_ mcp.Registry           = (*stagedRegistry)(nil)
+	_ mcp.ResourceRegistry   = (*stagedRegistry)(nil)
+	_ mcp.PromptRegistry     = (*stagedRegistry)(nil)
+	_ mcp.CompletionRegistry = (*stagedRegistry)(nil)
+)
+
+func (st *stagedRegistry) RegisterTool(name, description string, inputSchema map[string]interface{}, handler plugins.ToolHandler) {
+	st.RegisterServiceTool("", name, description, inputSchema, handler)
+}
+
+func (st *stagedRegistry) DefineService(info mcp.ServiceInfo) {
+	st.update.Services = append(st.update.Services, info)
+}
+
+func (st *stagedRegistry) RegisterServiceTool(service, name, description string, inputSchema map[string]interface{}, handler plugins.ToolHandler) {
+	st.update.Tools = append(st.update.Tools, mcp.ToolRegistration{
+		Service:     service,
+		Name:        name,
+		Description: description,
+		InputSchema: inputSchema,
+		Handler:     handler,
+	})
+}
+
+func (st *stagedRegistry) RegisterResource(uri, name, description, mimeType string, handler plugins.ResourceHandler) {
+	st.update.Resources = append(st.update.Resources, mcp.ResourceRegistration{
+		URI:         uri,
+		Name:        name,
+		Description: description,
+		MimeType:    mimeType,
+		Handler:     handler,
+	})
+}
+
+func (st *stagedRegistry) RegisterFileResource(uri, name, description, mimeType, path string) {
+	st.update.Resources = append(st.update.Resources, mcp.ResourceRegistration{
+		URI:         uri,
+		Name:        name,
+		Description: description,
+		MimeType:    mimeType,
+		Path:        path,
+	})
+}
+
+func (st *stagedRegistry) UnregisterResource(uri string) bool {
+	st.update.RemoveResources = append(st.update.RemoveResources, uri)
+	return true
+}
+
+func (st *stagedRegistry) RegisterPrompt(name, description string, arguments []mcp.PromptArgument, messages []mcp.PromptMessageTemplate) {
+	st.update.Prompts = append(st.update.Prompts, mcp.PromptRegistration{
+		Name:        name,
+		Description: description,
+		Arguments:   arguments,
+		Messages:    messages,
+	})
+}
+
+func (st *stagedRegistry) UnregisterPrompt(name string) bool {
+	st.update.RemovePrompts = append(st.update.RemovePrompts, name)
+	return true
+}
+
+func (st *stagedRegistry) RegisterCompletionValues(ref mcp.CompletionRef, argument string, values []string) {
+	st.update.Completions = append(st.update.Completions, mcp.CompletionRegistration{Ref: ref, Argument: argument, Values: values})
+}
+
+func (st *stagedRegistry) RegisterCompleter(ref mcp.CompletionRef, completer plugins.Completer) {
+	st.update.Completions = append(st.update.Completions, mcp.CompletionRegistration{Ref: ref, Completer: completer})
+}
+
+func (st *stagedRegistry) UnregisterCompletions(ref mcp.CompletionRef) {
+	st.update.RemoveCompletions = append(st.update.RemoveCompletions, ref)
+}
+
+// apply applies the staged changes to r in a single update when r supports
+// it. Other registries get the changes one by one, removals first, and skip
+// those they cannot hold.
+func (st *stagedRegistry) apply(r mcp.Registry) {
+	if ur, ok := r.(mcp.UpdatableRegistry); ok {
+		ur.ApplyUpdate(st.update)
+		return
+	}
+
+	u := st.update
+	if len(u.RemoveServices)+len(u.RemoveTools) > 0 {
+		logger.Warnf("registry does not support updates, keeping removed services and tools")
+	}
+	rr, hasResources := r.(mcp.ResourceRegistry)
+	pr, hasPrompts := r.(mcp.PromptRegistry)
+	cr, hasCompletions := r.(mcp.CompletionRegistry)
+	for _, uri := range u.RemoveResources {
+		if hasResources {
+			rr.UnregisterResource(uri)
+		}
+	}
+	for _, name := range u.RemovePrompts {
+		if hasPrompts {
+			pr.UnregisterPrompt(name)
+		}
+	}
+	for _, ref := range u.RemoveCompletions {
+		if hasCompletions {
+			cr.UnregisterCompletions(ref)
+		}
+	}
+
+	for _, info := range u.Services {
+		r.DefineService(info)
+	}
+	for _, tool := range u.Tools {
+		if tool.Limits != (mcp.ToolLimits{}) {
+			logger.Warnf("registry does not support tool limits, ignoring the limits of tool %s", tool.Name)
+		}
+		r.RegisterServiceTool(tool.Service, tool.Name, tool.Description, tool.InputSchema, tool.Handler)
+	}
+	for _, res := range u.Resources {
+		switch {
+		case !hasResources:
+			logger.Warnf("registry does not support resources, skipping resource %s", res.Name)
+		case res.Path != "":
+			rr.RegisterFileResource(res.URI, res.Name, res.Description, res.MimeType, res.Path)
+		default:
+			rr.RegisterResource(res.URI, res.Name, res.Description, res.MimeType, res.Handler)
+		}
+	}
+	for _, p := range u.Prompts {
+		if !hasPrompts {
+			logger.Warnf("registry does not support prompts, skipping prompt %s", p.Name)
+			continue
+		}
+		pr.RegisterPrompt(p.Name, p.Description, p.Arguments, p.Messages)
+	}
+	for _, c := range u.Completions {
+		switch {
+		case !hasCompletions:
+		case c.Completer != nil:
+			cr.RegisterCompleter(c.Ref, c.Completer)
+		default:
+			cr.RegisterCompletionValues(c.Ref, c.Argument, c.Values)
+		}
+	}
+}
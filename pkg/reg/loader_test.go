@@ -0,0 +1,394 @@
+package reg
+
+import (
+	"bytes"
+	"context"
+	"go/build"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/santoshkal/gomcp/pkg/mcp"
+	"github.com/santoshkal/gomcp/pkg/plugins"
+)
+
+// updateRecorder is a registry that records the updates applied to it.
+type updateRecorder struct {
+	updates []mcp.RegistryUpdate
+}
+
+func (r *updateRecorder) RegisterTool(name, description string, inputSchema map[string]interface{}, handler plugins.ToolHandler) {
+}
+
+func (r *updateRecorder) DefineService(info mcp.ServiceInfo) {}
+
+func (r *updateRecorder) RegisterServiceTool(service, name, description string, inputSchema map[string]interface{}, handler plugins.ToolHandler) {
+}
+
+func (r *updateRecorder) ApplyUpdate(update mcp.RegistryUpdate) {
+	r.updates = append(r.updates, update)
+}
+
+// tool returns the tool named name registered by the last update.
+func (r *updateRecorder) tool(t *testing.T, name string) mcp.ToolRegistration {
+	t.Helper()
+	if len(r.updates) == 0 {
+		t.Fatal("no update applied")
+	}
+	for _, tool := range r.updates[len(r.updates)-1].Tools {
+		if tool.Name == name {
+			return tool
+		}
+	}
+	t.Fatalf("tool %s not registered", name)
+	return mcp.ToolRegistration{}
+}
+
+// call invokes the handler of a registered tool.
+func call(t *testing.T, tool mcp.ToolRegistration, params map[string]interface{}) interface{} {
+	t.Helper()
+	result, err := tool.Handler(context.Background(), params)
+	if err != nil {
+		t.Fatalf("tool %s: %v", tool.Name, err)
+	}
+	return result
+}
+
+// useGOPATH makes a temporary directory the GOPATH plugins are loaded from and
+// returns it.
+func useGOPATH(t *testing.T) string {
+	t.Helper()
+	goPath := t.TempDir()
+	saved := build.Default.GOPATH
+	build.Default.GOPATH = goPath
+	t.Cleanup(func() { build.Default.GOPATH = saved })
+	return goPath
+}
+
+// kitSource is a plugin package whose Plugin value is declared by decl.
+func kitSource(decl string) string {
+	return `package kit
+
+import (
+	"context"
+
+	"github.com/santoshkal/gomcp/pkg/plugins"
+)
+
+type kit struct{}
+
+func (kit) Tools() []plugins.Tool { return []plugins.Tool{greet{}} }
+
+type greet struct{}
+
+func (greet) Name() string        { return "greet" }
+func (greet) Description() string { return "Greet someone" }
+func (greet) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"name": map[string]interface{}{"type": "string"}},
+	}
+}
+func (greet) Handle(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	return "hello " + params["name"].(string), nil
+}
+
+` + decl + "\n"
+}
+
+func TestLoaderPluginValue(t *testing.T) {
+	tests := []struct {
+		name string
+		decl string
+	}{
+		{name: "value", decl: "var Plugin = kit{}"},
+		{name: "pointer", decl: "var Plugin = &kit{}"},
+		{name: "interface", decl: "var Plugin plugins.Plugin = kit{}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			goPath := useGOPATH(t)
+			writeConfig(t, goPath, "src/example.com/"+tt.name+"/kit/kit.go", kitSource(tt.decl))
+			dir := t.TempDir()
+			path := writeConfig(t, dir, "gomcp.yaml", `
+services:
+  - name: Kit
+    enabled: true
+    plugin: example.com/`+tt.name+`/kit
+`)
+
+			r := &updateRecorder{}
+			result, err := NewLoader(path).Reload(r)
+			if err != nil {
+				t.Fatalf("Reload: %v", err)
+			}
+			if want := []string{"greet"}; !reflect.DeepEqual(result.AddedTools, want) {
+				t.Errorf("added tools = %q, want %q", result.AddedTools, want)
+			}
+			tool := r.tool(t, "greet")
+			if tool.Service != "Kit" || tool.Description != "Greet someone" {
+				t.Errorf("tool = %+v, want service Kit and the plugin's description", tool)
+			}
+			if got := call(t, tool, map[string]interface{}{"name": "gopher"}); got != "hello gopher" {
+				t.Errorf("result = %v, want hello gopher", got)
+			}
+		})
+	}
+}
+
+func TestLoaderPluginValueErrors(t *testing.T) {
+	goPath := useGOPATH(t)
+	writeConfig(t, goPath, "src/example.com/notkit/kit.go", "package kit\n\nvar Plugin = 42\n")
+	writeConfig(t, goPath, "src/example.com/nokit/kit.go", "package kit\n\nvar Other = 42\n")
+
+	tests := map[string]string{
+		"example.com/notkit": "Plugin of example.com/notkit does not implement plugins.Plugin",
+		"example.com/nokit":  "failed to retrieve Plugin symbol from example.com/nokit",
+	}
+	for importPath, want := range tests {
+		t.Run(importPath, func(t *testing.T) {
+			path := writeConfig(t, t.TempDir(), "gomcp.yaml", `
+services:
+  - name: Kit
+    enabled: true
+    plugin: `+importPath+`
+`)
+			_, err := NewLoader(path).Reload(&updateRecorder{})
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Fatalf("Reload error = %v, want one containing %q", err, want)
+			}
+		})
+	}
+}
+
+func TestLoaderReloadsChangedPlugin(t *testing.T) {
+	goPath := useGOPATH(t)
+	source := func(greeting string) string {
+		return `package greeter
+
+import "context"
+
+func Greet(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	return "` + greeting + ` " + params["name"].(string), nil
+}
+`
+	}
+	file := writeConfig(t, goPath, "src/example.com/go-greeter/greeter.go", source("hello"))
+	path := writeConfig(t, t.TempDir(), "gomcp.yaml", `
+services:
+  - name: Greeter
+    enabled: true
+    tools:
+      - name: greet
+        enabled: true
+        plugin: example.com/go-greeter
+        handler: greeter.Greet
+`)
+
+	r := &updateRecorder{}
+	l := NewLoader(path)
+	if _, err := l.Reload(r); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	params := map[string]interface{}{"name": "gopher"}
+	if got := call(t, r.tool(t, "greet"), params); got != "hello gopher" {
+		t.Errorf("result = %v, want hello gopher", got)
+	}
+
+	result, err := l.Reload(r)
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if !result.Empty() {
+		t.Errorf("unchanged reload = %+v, want no changes", result)
+	}
+
+	writeConfig(t, goPath, "src/example.com/go-greeter/greeter.go", source("howdy"))
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+	result, err = l.Reload(r)
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if want := []string{"greet"}; !reflect.DeepEqual(result.UpdatedTools, want) {
+		t.Errorf("updated tools = %q, want %q", result.UpdatedTools, want)
+	}
+	if got := call(t, r.tool(t, "greet"), params); got != "howdy gopher" {
+		t.Errorf("result = %v, want howdy gopher", got)
+	}
+}
+
+func TestLoaderHandlerQualifier(t *testing.T) {
+	goPath := useGOPATH(t)
+	writeConfig(t, goPath, "src/example.com/go-greeter/greeter.go", `package greeter
+
+import "context"
+
+func Greet(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	return "hello", nil
+}
+`)
+	path := writeConfig(t, t.TempDir(), "gomcp.yaml", `
+services:
+  - name: Greeter
+    enabled: true
+    tools:
+      - name: greet
+        enabled: true
+        plugin: example.com/go-greeter
+        handler: other.Greet
+`)
+	_, err := NewLoader(path).Reload(&updateRecorder{})
+	if err == nil {
+		t.Fatal("Reload succeeded with a handler qualified by another package")
+	}
+	if !strings.Contains(err.Error(), "greeter") {
+		t.Errorf("Reload error = %v, want one naming package greeter", err)
+	}
+}
+
+func TestLoaderReloadResult(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "motd.txt", "hello")
+	path := writeConfig(t, dir, "gomcp.yaml", `
+services:
+  - name: Docker
+    enabled: true
+    resources:
+      - uri: file:///motd
+        name: motd
+        enabled: true
+        path: motd.txt
+    prompts:
+      - name: pull
+        enabled: true
+        arguments:
+          - name: image
+            schema: {enum: [alpine, debian]}
+        template: "Pull {{.image}}"
+      - name: prune
+        enabled: true
+        template: "Prune"
+`)
+
+	r := &updateRecorder{}
+	l := NewLoader(path)
+	result, err := l.Reload(r)
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	want := &ReloadResult{
+		AddedServices:    []string{"Docker"},
+		AddedResources:   []string{"file:///motd"},
+		AddedPrompts:     []string{"prune", "pull"},
+		AddedCompletions: []string{"ref/prompt pull"},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("first reload = %v, want %v", result, want)
+	}
+
+	writeConfig(t, dir, "gomcp.yaml", `
+services:
+  - name: Docker
+    enabled: true
+    prompts:
+      - name: pull
+        enabled: true
+        arguments:
+          - name: image
+            schema: {enum: [alpine, debian, ubuntu]}
+        template: "Pull {{.image}}"
+      - name: run
+        enabled: true
+        template: "Run"
+`)
+	result, err = l.Reload(r)
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	want = &ReloadResult{
+		UpdatedServices:    []string{"Docker"},
+		RemovedResources:   []string{"file:///motd"},
+		AddedPrompts:       []string{"run"},
+		UpdatedPrompts:     []string{"pull"},
+		RemovedPrompts:     []string{"prune"},
+		UpdatedCompletions: []string{"ref/prompt pull"},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("second reload = %v, want %v", result, want)
+	}
+	if got := result.String(); got != "updated services [Docker], removed resources [file:///motd], added prompts [run], updated prompts [pull], removed prompts [prune], updated completions [ref/prompt pull]" {
+		t.Errorf("String() = %q", got)
+	}
+
+	writeConfig(t, dir, "gomcp.yaml", "services: []\n")
+	result, err = l.Reload(r)
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	want = &ReloadResult{
+		RemovedServices:    []string{"Docker"},
+		RemovedPrompts:     []string{"pull", "run"},
+		RemovedCompletions: []string{"ref/prompt pull"},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("third reload = %v, want %v", result, want)
+	}
+}
+
+func TestLoaderLogsPluginOutput(t *testing.T) {
+	var out bytes.Buffer
+	l := logrus.New()
+	l.SetOutput(&out)
+	l.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true})
+	saved := logger
+	SetLogger(l)
+	t.Cleanup(func() { SetLogger(saved) })
+
+	goPath := useGOPATH(t)
+	writeConfig(t, goPath, "src/example.com/chatty/chatty.go", `package chatty
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+func init() { fmt.Println("starting") }
+
+func Handler(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	log.SetFlags(0)
+	log.Println("handling")
+	return "done", nil
+}
+`)
+	path := writeConfig(t, t.TempDir(), "gomcp.yaml", `
+services:
+  - name: Chatty
+    enabled: true
+    tools:
+      - name: chat
+        enabled: true
+        plugin: example.com/chatty
+`)
+	r := &updateRecorder{}
+	if _, err := NewLoader(path).Reload(r); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	call(t, r.tool(t, "chat"), nil)
+
+	for _, want := range []string{
+		`level=info msg=starting plugin=example.com/chatty`,
+		`level=warning msg=handling plugin=example.com/chatty`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("log does not contain %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package reg
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// logger reports problems found while applying a configuration. It is the
+// standard logrus logger unless the server shares its own with SetLogger.
+var logger = logrus.StandardLogger()
+
+// SetLogger makes the package log through l.
+func SetLogger(l *logrus.Logger) {
+	logger = l
+}
+
+// pluginOutput logs what a plugin prints, one entry per line, tagged with the
+// plugin's import path. A line is logged once its newline is written.
+type pluginOutput struct {
+	plugin string
+	level  logrus.Level
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (w *pluginOutput) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		logger.WithField("plugin", w.plugin).Log(w.level, string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
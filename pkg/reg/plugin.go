@@ -0,0 +1,251 @@
+package reg
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/traefik/yaegi/interp"
+	"github.com/traefik/yaegi/stdlib"
+	"github.com/traefik/yaegi/stdlib/unsafe"
+
+	"github.com/santoshkal/gomcp/pkg/plugins"
+)
+
+// pluginInterpreters caches the interpreters of the imported plugins.
+var pluginInterpreters = &pluginCache{plugins: make(map[string]*loadedPlugin)}
+
+// pluginCache imports each plugin package once, in its own Yaegi interpreter,
+// and shares it between the tools, resources and completers that name it. A
+// plugin is imported again when the files of its package, or of the GOPATH
+// packages it imports, have changed since. Reloads compare the same stamps, so
+// they register the tools of a changed plugin again and these run the new code.
+type pluginCache struct {
+	mu      sync.Mutex
+	plugins map[string]*loadedPlugin // by import path
+}
+
+// loadedPlugin is a plugin package imported in an interpreter.
+type loadedPlugin struct {
+	interp *interp.Interpreter
+	stamp  string         // state of the package files when imported
+	name   string         // package name in the package clause
+	funcs  []string       // exported function names, sorted
+	plugin plugins.Plugin // exported Plugin value, once retrieved
+}
+
+// loadPluginSymbol returns the named exported symbol of the plugin package at
+// importPath. Plugins run with an empty environment; values they need are
+// granted as secrets.
+func loadPluginSymbol(importPath, symbol string) (reflect.Value, error) {
+	return pluginInterpreters.symbol(importPath, symbol)
+}
+
+// pluginTools returns the tools of the Plugin value exported by the plugin
+// package at importPath.
+func pluginTools(importPath string) ([]plugins.Tool, error) {
+	p, err := pluginInterpreters.plugin(importPath)
+	if err != nil {
+		return nil, err
+	}
+	return p.Tools(), nil
+}
+
+// pluginTool returns the named tool of the Plugin exported by importPath.
+func pluginTool(importPath, name string) (plugins.Tool, error) {
+	tools, err := pluginTools(importPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tools {
+		if t.Name() == name {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("Plugin of %s provides no tool %s", importPath, name)
+}
+
+// pluginPackage returns the package name of the plugin package at importPath.
+func pluginPackage(importPath string) (string, error) {
+	p, err := pluginInterpreters.load(importPath)
+	if err != nil {
+		return "", err
+	}
+	return p.name, nil
+}
+
+// pluginFunctions returns the sorted names of the functions exported by the
+// plugin package at importPath.
+func pluginFunctions(importPath string) ([]string, error) {
+	p, err := pluginInterpreters.load(importPath)
+	if err != nil {
+		return nil, err
+	}
+	return p.funcs, nil
+}
+
+// symbol returns the named exported symbol of a plugin.
+func (c *pluginCache) symbol(importPath, symbol string) (reflect.Value, error) {
+	p, err := c.load(importPath)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	// Evaluations share the interpreter, so they are serialized.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, err := p.interp.Eval("plugin." + symbol)
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("failed to retrieve %s symbol from %s: %v", symbol, importPath, err)
+	}
+	return v, nil
+}
+
+// plugin returns the Plugin value exported by a plugin. It is assigned to a
+// plugins.Plugin variable within the interpreter, which wraps interpreted
+// types, so it may be declared with any type that implements the interface,
+// e.g. var Plugin = kit{}.
+func (c *pluginCache) plugin(importPath string) (plugins.Plugin, error) {
+	p, err := c.load(importPath)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p.plugin != nil {
+		return p.plugin, nil
+	}
+	if _, err := p.interp.Eval("plugin.Plugin"); err != nil {
+		return nil, fmt.Errorf("failed to retrieve Plugin symbol from %s: %v", importPath, err)
+	}
+	if _, err := p.interp.Eval("var pluginValue plugins.Plugin = plugin.Plugin"); err != nil {
+		return nil, fmt.Errorf("Plugin of %s does not implement plugins.Plugin: %v", importPath, err)
+	}
+	v, err := p.interp.Eval("pluginValue")
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve Plugin of %s: %v", importPath, err)
+	}
+	value, ok := v.Interface().(plugins.Plugin)
+	if !ok || value == nil {
+		return nil, fmt.Errorf("Plugin of %s does not implement plugins.Plugin", importPath)
+	}
+	p.plugin = value
+	return value, nil
+}
+
+// load returns the plugin at importPath, importing it when it was not
+// imported yet or its files changed since.
+func (c *pluginCache) load(importPath string) (*loadedPlugin, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	goPath, dir := pluginDir(importPath)
+	stamp := packageStamp(dir)
+	if p, ok := c.plugins[importPath]; ok && p.stamp == stamp {
+		return p, nil
+	}
+	logger.Debugf("Loading plugin %s from GOPATH %s", importPath, goPath)
+
+	// Create a new yaegi interpreter instance. What the plugin prints with the
+	// fmt and log packages is logged.
+	i := interp.New(interp.Options{
+		GoPath: goPath,
+		Stdout: &pluginOutput{plugin: importPath, level: logrus.InfoLevel},
+		Stderr: &pluginOutput{plugin: importPath, level: logrus.WarnLevel},
+	})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		logger.Errorf("error loading package symbols: %v", err)
+	}
+	if err := i.Use(unsafe.Symbols); err != nil {
+		logger.Errorf("error loading unsafe symbols: %v", err)
+	}
+
+	if err := i.Use(plugins.HandlerSymbols()); err != nil {
+		logger.Errorf("error loading handler symbols: %v", err)
+	}
+
+	// Import the package under a fixed name so its symbols can be referenced
+	// regardless of the package clause.
+	if _, err := i.Eval(fmt.Sprintf(`import plugin "%s"`, importPath)); err != nil {
+		return nil, fmt.Errorf("failed to evaluate plugin %s: %+v", importPath, err)
+	}
+	if _, err := i.Eval(`import plugins "github.com/santoshkal/gomcp/pkg/plugins"`); err != nil {
+		return nil, fmt.Errorf("failed to import the plugin API for %s: %v", importPath, err)
+	}
+
+	p := &loadedPlugin{interp: i, stamp: stamp}
+	if pkg, err := build.ImportDir(dir, 0); err == nil {
+		p.name = pkg.Name
+	}
+	for name, v := range i.Symbols(importPath)[importPath] {
+		if v.Kind() == reflect.Func {
+			p.funcs = append(p.funcs, name)
+		}
+	}
+	sort.Strings(p.funcs)
+	c.plugins[importPath] = p
+	return p, nil
+}
+
+// pluginStamp identifies the state of the plugin package at importPath and of
+// the GOPATH packages it imports.
+func pluginStamp(importPath string) string {
+	_, dir := pluginDir(importPath)
+	return packageStamp(dir)
+}
+
+// pluginDir returns the GOPATH entry holding the plugin package at importPath
+// and the package directory. The interpreter is given that entry alone, as it
+// does not search a list of them. When no entry holds the package, the first
+// one is returned along with an empty dir.
+func pluginDir(importPath string) (goPath, dir string) {
+	roots := filepath.SplitList(build.Default.GOPATH)
+	for _, root := range roots {
+		dir := filepath.Join(root, "src", importPath)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return root, dir
+		}
+	}
+	if len(roots) == 0 {
+		return "", ""
+	}
+	return roots[0], ""
+}
+
+// packageStamp identifies the state of the Go files of the package in dir and
+// of the GOPATH packages it imports, or is empty when dir cannot be read.
+func packageStamp(dir string) string {
+	var stamp strings.Builder
+	seen := make(map[string]bool)
+	var add func(dir string)
+	add = func(dir string) {
+		if dir == "" || seen[dir] {
+			return
+		}
+		seen[dir] = true
+		files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+		sort.Strings(files)
+		for _, file := range files {
+			info, err := os.Stat(file)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(&stamp, "%s-%d-%d,", file, info.ModTime().UnixNano(), info.Size())
+		}
+		pkg, err := build.ImportDir(dir, 0)
+		if err != nil {
+			return
+		}
+		for _, path := range pkg.Imports {
+			_, imported := pluginDir(path)
+			add(imported)
+		}
+	}
+	add(dir)
+	return stamp.String()
+}
@@ -2,31 +2,67 @@
 package reg
 
 import (
-	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
-	"go/build"
-	"os"
-
-	"github.com/traefik/yaegi/interp"
-	"github.com/traefik/yaegi/stdlib"
-	"github.com/traefik/yaegi/stdlib/unsafe"
-	"gopkg.in/yaml.v2"
+	"reflect"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/santoshkal/gomcp/pkg/mcp"
 	"github.com/santoshkal/gomcp/pkg/plugins"
 )
 
-// Config represents the overall YAML configuration.
+// Config represents the overall YAML configuration. String values may
+// reference environment variables as ${VAR} or ${VAR:-default}.
 type Config struct {
-	Services []ServiceConfig `yaml:"services"`
+	// Include lists files, directories or glob patterns merged into the
+	// configuration; see loadConfig.
+	Include  []string                `yaml:"include"`
+	Server   ServerConfig            `yaml:"server"`
+	Secrets  map[string]SecretConfig `yaml:"secrets"`
+	Services []ServiceConfig         `yaml:"services"`
+
+	secrets plugins.Secrets   // resolved values of Secrets
+	files   []string          // files and directories the configuration was read from
+	stamps  map[string]string // state of the plugin packages when staged, by import path
+}
+
+// SecretConfig defines where the value of a secret is read from. Exactly one
+// of Env, File and Value is set.
+type SecretConfig struct {
+	Env   string `yaml:"env"`   // Environment variable holding the value.
+	File  string `yaml:"file"`  // File holding the value, relative to the config file.
+	Value string `yaml:"value"` // Literal value, usually a ${VAR} reference.
+}
+
+// toolSecrets returns the values of the secrets granted to tool.
+func (c *Config) toolSecrets(tool ToolConfig) plugins.Secrets {
+	if len(tool.Secrets) == 0 {
+		return nil
+	}
+	secrets := make(plugins.Secrets, len(tool.Secrets))
+	for _, name := range tool.Secrets {
+		secrets[name] = c.secrets[name]
+	}
+	return secrets
 }
 
 // ServiceConfig defines a service entry.
 type ServiceConfig struct {
-	Name    string       `yaml:"name"`
-	Enabled bool         `yaml:"enabled"` // if false, skip this service
-	Tools   []ToolConfig `yaml:"tools"`
+	Name        string           `yaml:"name"`
+	Description string           `yaml:"description"`
+	Version     string           `yaml:"version"`
+	Enabled     bool             `yaml:"enabled"` // if false, the service is declared but its tools are not loaded
+	Tools       []ToolConfig     `yaml:"tools"`
+	Resources   []ResourceConfig `yaml:"resources"`
+	Prompts     []PromptConfig   `yaml:"prompts"`
+	// Plugin is a package exporting a Plugin value whose tools are added to the
+	// service. Tool entries without a plugin configure the plugin tool of the
+	// same name, which keeps the plugin's description and schema unless set.
+	Plugin string `yaml:"plugin"`
 }
 
 // ToolConfig defines an individual tool.
@@ -36,85 +72,347 @@ type ToolConfig struct {
 	Enabled     bool                   `yaml:"enabled"` // if false, skip this tool
 	Schema      map[string]interface{} `yaml:"schema"`
 	Plugin      string                 `yaml:"plugin"` // Inline Go code for the handler.
+	// Handler names the exported function of the plugin that handles the tool,
+	// optionally qualified with the package name, e.g. plug.CreateNetwork.
+	Handler string `yaml:"handler"`
+	// Secrets names the secrets the handler can read with plugins.SecretFromContext.
+	Secrets []string `yaml:"secrets"`
+
+	Timeout        time.Duration `yaml:"timeout"`         // Per attempt; server.timeouts.tool when unset.
+	Retries        int           `yaml:"retries"`         // Attempts repeated after a plugins.Retryable error.
+	RetryBackoff   time.Duration `yaml:"retry_backoff"`   // Delay before the first retry, doubled for each one.
+	MaxConcurrency int           `yaml:"max_concurrency"` // Calls running at once; unlimited when unset.
+
+	fromPlugin bool // handled by the Tool of the service's Plugin
 }
 
-// loadConfig reads and unmarshals the YAML file.
-func loadConfig(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read YAML config: %w", err)
-	}
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal YAML: %w", err)
-	}
-	return &cfg, nil
+// ResourceConfig defines a read-only resource. A URI containing {variables}
+// declares a URI template whose values are passed to the plugin.
+type ResourceConfig struct {
+	URI         string `yaml:"uri"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	MimeType    string `yaml:"mime_type"`
+	Enabled     bool   `yaml:"enabled"` // if false, skip this resource
+	Path        string `yaml:"path"`    // Local file served as the resource contents, relative to the configuration file.
+	Plugin      string `yaml:"plugin"`  // Package exporting a ReadResource function.
+	// Schema describes the template variables as object properties; enum values
+	// are offered as completions.
+	Schema    map[string]interface{} `yaml:"schema"`
+	Completer string                 `yaml:"completer"` // Package exporting a Complete function.
+}
+
+// PromptConfig defines a parameterized prompt template. Message text is rendered
+// with Go text/template, e.g. "Pull {{.image}}". Template is shorthand for a
+// single user message.
+type PromptConfig struct {
+	Name        string                 `yaml:"name"`
+	Description string                 `yaml:"description"`
+	Enabled     bool                   `yaml:"enabled"` // if false, skip this prompt
+	Arguments   []PromptArgumentConfig `yaml:"arguments"`
+	Template    string                 `yaml:"template"`
+	Messages    []PromptMessageConfig  `yaml:"messages"`
+	Completer   string                 `yaml:"completer"` // Package exporting a Complete function.
+}
+
+// PromptArgumentConfig defines an argument accepted by a prompt. Enum values of
+// its schema are offered as completions.
+type PromptArgumentConfig struct {
+	Name        string                 `yaml:"name"`
+	Description string                 `yaml:"description"`
+	Required    bool                   `yaml:"required"`
+	Schema      map[string]interface{} `yaml:"schema"`
+}
+
+// PromptMessageConfig defines one templated message of a prompt.
+type PromptMessageConfig struct {
+	Role     string `yaml:"role"` // user or assistant
+	Template string `yaml:"template"`
 }
 
 // RegisterToolsFromConfig loads the configuration, evaluates each tool's script using Yaegi,
-// and registers only the enabled tools using the provided Registry.
+// and registers only the enabled tools using the provided Registry. Nothing is
+// registered if any plugin fails to load.
 func RegisterToolsFromConfig(r mcp.Registry, configPath string) error {
-	cfg, err := loadConfig(configPath)
+	_, err := NewLoader(configPath).Reload(r)
+	return err
+}
+
+// registerTool loads the handler of a tool and stages it under service with its
+// limits. The handler is called with the given secrets in its context.
+func registerTool(r *stagedRegistry, service string, tool ToolConfig, secrets plugins.Secrets) error {
+	handler, err := toolHandler(tool)
 	if err != nil {
 		return err
 	}
 
-	for _, svc := range cfg.Services {
-		if !svc.Enabled {
+	// Register the tool. A missing schema stays nil, so the registry fills in
+	// its default.
+	var schema map[string]interface{}
+	if tool.Schema != nil {
+		schema, _ = normalizeYAML(tool.Schema).(map[string]interface{})
+	}
+	r.update.Tools = append(r.update.Tools, mcp.ToolRegistration{
+		Service:     service,
+		Name:        tool.Name,
+		Description: tool.Description,
+		InputSchema: schema,
+		Handler:     withSecrets(handler, secrets),
+		Limits: mcp.ToolLimits{
+			Timeout:        tool.Timeout,
+			Retries:        tool.Retries,
+			RetryBackoff:   tool.RetryBackoff,
+			MaxConcurrency: tool.MaxConcurrency,
+		},
+	})
+	return nil
+}
+
+// expandPluginTools adds the tools of service plugins to the tools of their
+// services. Each becomes a tool entry, or is merged into the entry of the same
+// name that sets no plugin of its own.
+func expandPluginTools(cfg *Config) error {
+	for i := range cfg.Services {
+		svc := &cfg.Services[i]
+		if svc.Plugin == "" {
 			continue
 		}
-		for _, tool := range svc.Tools {
-			if !tool.Enabled {
-				continue
+		provided, err := pluginTools(svc.Plugin)
+		if err != nil {
+			return fmt.Errorf("failed to load tools of service %s: %v", svc.Name, err)
+		}
+
+		// Entries that only name a handler use a function of the service plugin.
+		configured := make(map[string]int)
+		for j := range svc.Tools {
+			tool := &svc.Tools[j]
+			switch {
+			case tool.Plugin == "" && tool.Handler != "":
+				tool.Plugin = svc.Plugin
+			case tool.Plugin == "":
+				configured[tool.Name] = j
+			}
+		}
+		for _, t := range provided {
+			schema, err := jsonSchema(t.Schema())
+			if err != nil {
+				return fmt.Errorf("invalid schema of tool %s of plugin %s: %v", t.Name(), svc.Plugin, err)
 			}
-			goPath := build.Default.GOPATH
-			fmt.Printf("GoPath: %v\n", goPath)
-
-			// Create a new yaegi interpreter instance.
-			var stdout, stderr bytes.Buffer
-			i := interp.New(interp.Options{GoPath: goPath, Env: os.Environ(), Stdout: &stdout, Stderr: &stderr})
-			if err := i.Use(stdlib.Symbols); err != nil {
-				fmt.Printf("error loading package symbols: %v\n", err)
+			if err := validatePluginSchema(svc.Plugin, t.Name(), schema); err != nil {
+				return err
 			}
-			if err := i.Use(unsafe.Symbols); err != nil {
-				fmt.Printf("error loading unsafe symbols: %v", err)
+			j, ok := configured[t.Name()]
+			if !ok {
+				svc.Tools = append(svc.Tools, ToolConfig{Name: t.Name(), Enabled: true, Description: t.Description(), Schema: schema})
+				j = len(svc.Tools) - 1
 			}
+			delete(configured, t.Name())
 
-			if err := i.Use(plugins.HandlerSymbols()); err != nil {
-				fmt.Printf("error loading handler symbols: %v\n", err)
+			tool := &svc.Tools[j]
+			tool.Plugin, tool.fromPlugin = svc.Plugin, true
+			if tool.Description == "" {
+				tool.Description = t.Description()
+			}
+			if tool.Schema == nil {
+				tool.Schema = schema
 			}
+		}
+		for _, tool := range svc.Tools {
+			if _, ok := configured[tool.Name]; ok {
+				return fmt.Errorf("tool %s of service %s sets no plugin and is not provided by %s", tool.Name, svc.Name, svc.Plugin)
+			}
+		}
+	}
+	return nil
+}
 
-			// code, err := os.ReadFile(tool.Script)
-			// if err != nil {
-			// 	fmt.Printf("error reading plugin file: %v", err)
-			// }
+// jsonSchema converts a schema built by a plugin to its JSON form, so that it
+// holds the same types as a schema read from YAML.
+func jsonSchema(schema map[string]interface{}) (map[string]interface{}, error) {
+	if schema == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	var converted map[string]interface{}
+	err = json.Unmarshal(data, &converted)
+	return converted, err
+}
 
-			// Evaluate the provided script. The script must define a function "Handler".
-			if _, err := i.Eval(fmt.Sprintf(`import "%s"`, tool.Plugin)); err != nil {
-				return fmt.Errorf("failed to evaluate plugin for tool [%s]: %+v", tool.Name, err)
-			}
+// toolHandler resolves the handler of a tool from its plugin. Tools of a
+// service plugin are handled by the plugin's Tool, tools that name a handler by
+// that function, and other tools by the plugin's Handler function.
+func toolHandler(tool ToolConfig) (plugins.ToolHandler, error) {
+	if tool.fromPlugin {
+		t, err := pluginTool(tool.Plugin, tool.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tool %s: %v", tool.Name, err)
+		}
+		return t.Handle, nil
+	}
+	if tool.Handler == "" {
+		return pluginHandler(tool.Plugin, "Handler", tool.Name)
+	}
 
-			// if _, err := i.Eval(string(code)); err != nil {
-			// 	fmt.Printf("error evaluating plugin code: %v", err)
-			// }
+	function := tool.Handler
+	if qualifier, name, ok := strings.Cut(tool.Handler, "."); ok {
+		pkg, err := pluginPackage(tool.Plugin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load plugin for tool %s: %v", tool.Name, err)
+		}
+		if qualifier != pkg {
+			return nil, fmt.Errorf("handler %s of tool %s must be qualified with %s, the package of plugin %s", tool.Handler, tool.Name, pkg, tool.Plugin)
+		}
+		function = name
+	}
+	return pluginHandler(tool.Plugin, function, tool.Name)
+}
 
-			fmt.Printf("Script Path: %v\n ", tool.Plugin)
+// pluginHandler returns the exported function of a plugin that handles a tool.
+// When the plugin has no such function, the error lists the functions it does
+// export.
+func pluginHandler(importPath, function, toolName string) (plugins.ToolHandler, error) {
+	functions, err := pluginFunctions(importPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load plugin for tool %s: %v", toolName, err)
+	}
+	if !slices.Contains(functions, function) {
+		exported := strings.Join(functions, ", ")
+		if exported == "" {
+			exported = "none"
+		}
+		return nil, fmt.Errorf("plugin %s has no function %s for tool %s; exported functions: %s", importPath, function, toolName, exported)
+	}
 
-			// Retrieve the Handler symbol.
-			v, err := i.Eval("Handler")
+	// Retrieve the handler symbol.
+	v, err := loadPluginSymbol(importPath, function)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s for tool %s: %v", function, toolName, err)
+	}
+
+	// Assert that the symbol has the correct signature.
+	handler, ok := asToolHandler(v)
+	if !ok {
+		return nil, fmt.Errorf("%s of plugin %s for tool %s has type %s, expected func(context.Context, map[string]interface{}) (interface{}, error)", function, importPath, toolName, v.Type())
+	}
+	return handler, nil
+}
+
+// asToolHandler converts a plugin function to a tool handler.
+func asToolHandler(v reflect.Value) (plugins.ToolHandler, bool) {
+	switch h := v.Interface().(type) {
+	case plugins.ToolHandler:
+		return h, h != nil
+	case func(context.Context, map[string]interface{}) (interface{}, error):
+		return h, h != nil
+	}
+	return nil, false
+}
+
+// withSecrets wraps handler so that it is called with secrets in its context.
+func withSecrets(handler plugins.ToolHandler, secrets plugins.Secrets) plugins.ToolHandler {
+	if len(secrets) == 0 {
+		return handler
+	}
+	return func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return handler(plugins.WithSecrets(ctx, secrets), params)
+	}
+}
+
+// registerResources registers the enabled resources of a service.
+func registerResources(r mcp.ResourceRegistry, resources []ResourceConfig) error {
+	for _, res := range resources {
+		if !res.Enabled {
+			continue
+		}
+		switch {
+		case res.Path != "" && res.Plugin != "":
+			return fmt.Errorf("resource %s must set either path or plugin, not both", res.Name)
+		case res.Path != "":
+			r.RegisterFileResource(res.URI, res.Name, res.Description, res.MimeType, res.Path)
+		case res.Plugin != "":
+			v, err := loadPluginSymbol(res.Plugin, "ReadResource")
 			if err != nil {
-				return fmt.Errorf("failed to retrieve Handler symbol for tool %s: %v", tool.Name, err)
+				return fmt.Errorf("failed to load ReadResource for resource %s: %v", res.Name, err)
 			}
-
-			// Assert that the symbol has the correct signature.
-			handler, ok := v.Interface().(func(context.Context, map[string]interface{}) (interface{}, error))
+			handler, ok := v.Interface().(func(context.Context, string, map[string]string) (interface{}, error))
 			if !ok {
-				return fmt.Errorf("handler for tool %s does not have the correct signature", tool.Name)
+				return fmt.Errorf("ReadResource for resource %s does not have the correct signature", res.Name)
 			}
+			r.RegisterResource(res.URI, res.Name, res.Description, res.MimeType, handler)
+		default:
+			return fmt.Errorf("resource %s must set path or plugin", res.Name)
+		}
+	}
+	return nil
+}
 
-			// Register the tool.
-			r.RegisterTool(tool.Name, tool.Description, tool.Schema, handler)
+// registerPrompts compiles and registers the enabled prompts of a service.
+func registerPrompts(r mcp.PromptRegistry, prompts []PromptConfig) error {
+	for _, p := range prompts {
+		if !p.Enabled {
+			continue
+		}
+
+		var arguments []mcp.PromptArgument
+		for _, arg := range p.Arguments {
+			if arg.Name == "" {
+				return fmt.Errorf("prompt %s has an argument without a name", p.Name)
+			}
+			arguments = append(arguments, mcp.PromptArgument{Name: arg.Name, Description: arg.Description, Required: arg.Required})
+		}
+
+		msgConfigs := p.Messages
+		if p.Template != "" {
+			msgConfigs = append([]PromptMessageConfig{{Role: "user", Template: p.Template}}, msgConfigs...)
+		}
+		if len(msgConfigs) == 0 {
+			return fmt.Errorf("prompt %s must define a template or messages", p.Name)
 		}
+
+		var messages []mcp.PromptMessageTemplate
+		for i, msg := range msgConfigs {
+			if msg.Role != "user" && msg.Role != "assistant" {
+				return fmt.Errorf("prompt %s message %d has invalid role %q: expected user or assistant", p.Name, i, msg.Role)
+			}
+			tmpl, err := template.New(fmt.Sprintf("%s[%d]", p.Name, i)).Option("missingkey=zero").Parse(msg.Template)
+			if err != nil {
+				return fmt.Errorf("failed to parse template of prompt %s: %v", p.Name, err)
+			}
+			messages = append(messages, mcp.PromptMessageTemplate{Role: msg.Role, Template: tmpl})
+		}
+
+		r.RegisterPrompt(p.Name, p.Description, arguments, messages)
 	}
 	return nil
 }
+
+// normalizeYAML converts any map[interface{}]interface{} values, which YAML
+// decoders produce for non-string keys, into map[string]interface{} so that
+// schemas can be encoded as JSON.
+func normalizeYAML(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[fmt.Sprint(k)] = normalizeYAML(item)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[k] = normalizeYAML(item)
+		}
+		return m
+	case []interface{}:
+		items := make([]interface{}, len(val))
+		for i, item := range val {
+			items[i] = normalizeYAML(item)
+		}
+		return items
+	default:
+		return v
+	}
+}
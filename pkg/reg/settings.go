@@ -0,0 +1,109 @@
+package reg
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultConfigFile is the configuration file looked up in the XDG config
+// directory and the working directory.
+const DefaultConfigFile = "plug.yaml"
+
+// ServerConfig holds the server-level settings of the `server` section.
+type ServerConfig struct {
+	Address   string        `yaml:"address"`   // HTTP listen address
+	Transport string        `yaml:"transport"` // http or stdio
+	LogLevel  string        `yaml:"log_level"` // debug, info, warn or error
+	LLM       LLMConfig     `yaml:"llm"`
+	Timeouts  TimeoutConfig `yaml:"timeouts"`
+	// AllowedOrigins lists the browser origins, e.g. https://app.example.com,
+	// that may use the Streamable HTTP endpoint besides loopback ones.
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+// LLMConfig selects the model used to plan plain language instructions.
+type LLMConfig struct {
+	// Provider is openai, or sampling to use the client's model. When empty,
+	// openai is used if OPENAI_API_KEY is set and sampling otherwise.
+	Provider string `yaml:"provider"`
+	Model    string `yaml:"model"`
+}
+
+// TimeoutConfig bounds how long handlers may run, e.g. "30s".
+type TimeoutConfig struct {
+	Tool       time.Duration `yaml:"tool"`
+	Resource   time.Duration `yaml:"resource"`
+	Completion time.Duration `yaml:"completion"`
+	// Session is how long a Streamable HTTP session may stay idle, with no
+	// open stream and no request in flight, before it expires.
+	Session time.Duration `yaml:"session"`
+}
+
+// Server settings used when the configuration leaves them unset.
+const (
+	DefaultAddress           = ":1234"
+	DefaultTransport         = "http"
+	DefaultLogLevel          = "debug"
+	DefaultModel             = "gpt-4o"
+	DefaultToolTimeout       = 30 * time.Second
+	DefaultResourceTimeout   = 30 * time.Second
+	DefaultCompletionTimeout = 10 * time.Second
+	DefaultSessionTimeout    = 30 * time.Minute
+)
+
+// WithDefaults returns the settings with unset fields filled in.
+func (c ServerConfig) WithDefaults() ServerConfig {
+	if c.Address == "" {
+		c.Address = DefaultAddress
+	}
+	if c.Transport == "" {
+		c.Transport = DefaultTransport
+	}
+	if c.LogLevel == "" {
+		c.LogLevel = DefaultLogLevel
+	}
+	if c.LLM.Model == "" {
+		c.LLM.Model = DefaultModel
+	}
+	if c.Timeouts.Tool == 0 {
+		c.Timeouts.Tool = DefaultToolTimeout
+	}
+	if c.Timeouts.Resource == 0 {
+		c.Timeouts.Resource = DefaultResourceTimeout
+	}
+	if c.Timeouts.Completion == 0 {
+		c.Timeouts.Completion = DefaultCompletionTimeout
+	}
+	if c.Timeouts.Session == 0 {
+		c.Timeouts.Session = DefaultSessionTimeout
+	}
+	return c
+}
+
+// ResolveConfigPath returns the configuration file to load. It prefers
+// MCP_CONFIG_PATH, which the -config flag sets, then gomcp/plug.yaml in the XDG
+// config directory ($XDG_CONFIG_HOME or ~/.config), then plug.yaml in the
+// working directory.
+func ResolveConfigPath() string {
+	if path := os.Getenv("MCP_CONFIG_PATH"); path != "" {
+		return path
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		path := filepath.Join(dir, "gomcp", DefaultConfigFile)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return DefaultConfigFile
+}
+
+// LoadServerConfig reads the server settings of the configuration file at
+// path, with defaults applied.
+func LoadServerConfig(path string) (ServerConfig, error) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return ServerConfig{}.WithDefaults(), err
+	}
+	return cfg.Server.WithDefaults(), nil
+}
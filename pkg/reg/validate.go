@@ -0,0 +1,607 @@
+package reg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/santoshkal/gomcp/pkg/plugins"
+)
+
+// ConfigError is a problem found at a line of a configuration file.
+type ConfigError struct {
+	File    string
+	Line    int // 0 when the problem is not tied to a line
+	Message string
+}
+
+func (e *ConfigError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.File, e.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+}
+
+// ConfigErrors lists every problem found in a configuration file, by line.
+type ConfigErrors []*ConfigError
+
+func (e ConfigErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// ValidateConfig checks the configuration file at path without applying it.
+// When loadPlugins is set, every plugin is also loaded and its symbols checked.
+func ValidateConfig(path string, loadPlugins bool) error {
+	cfg, err := loadConfig(path)
+	if err != nil || !loadPlugins {
+		return err
+	}
+	_, _, err = stageChanges(&Config{}, cfg)
+	return err
+}
+
+// handlerPattern matches an exported function name, optionally qualified
+// with a package name.
+var handlerPattern = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*\.)?[A-Z][A-Za-z0-9_]*$`)
+
+// yamlLinePattern matches the line prefix of yaml.v3 error messages.
+var yamlLinePattern = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// yamlError converts a yaml.v3 error message into a ConfigError.
+func yamlError(file, msg string) *ConfigError {
+	err := &ConfigError{File: file, Message: msg}
+	if m := yamlLinePattern.FindStringSubmatch(msg); m != nil {
+		err.Line, _ = strconv.Atoi(m[1])
+		err.Message = m[2]
+	}
+	return err
+}
+
+// validator walks the YAML nodes of the files of a configuration and collects
+// the problems the decoder cannot detect. Nodes of the wrong kind are skipped,
+// as the decoder has already reported them.
+type validator struct {
+	file    string // file being read or validated
+	errs    ConfigErrors
+	defined map[string]string // location of each named definition, by kind and name
+	secrets plugins.Secrets   // resolved values of the secrets sections
+}
+
+func newValidator() *validator {
+	return &validator{defined: make(map[string]string), secrets: make(plugins.Secrets)}
+}
+
+func (v *validator) errorf(n *yaml.Node, format string, args ...interface{}) {
+	v.errs = append(v.errs, &ConfigError{File: v.file, Line: n.Line, Message: fmt.Sprintf(format, args...)})
+}
+
+// define records where a named definition was found and reports it when the
+// name was already defined, in this file or another one.
+func (v *validator) define(kind, name string, n *yaml.Node) {
+	key := kind + " " + name
+	if at, dup := v.defined[key]; dup {
+		v.errorf(n, "duplicate %s %s, first defined at %s", kind, name, at)
+		return
+	}
+	v.defined[key] = fmt.Sprintf("%s:%d", v.file, n.Line)
+}
+
+// result returns the collected errors sorted by file and line, or nil.
+func (v *validator) result() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(v.errs, func(i, j int) bool {
+		if v.errs[i].File != v.errs[j].File {
+			return v.errs[i].File < v.errs[j].File
+		}
+		return v.errs[i].Line < v.errs[j].Line
+	})
+	return v.errs
+}
+
+// field returns the value of key in a mapping node, or nil.
+func field(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// items returns the elements of a sequence node, or nil.
+func items(n *yaml.Node) []*yaml.Node {
+	if n == nil || n.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return n.Content
+}
+
+// scalar returns the value of a scalar field of n and the node it was read
+// from, which is n itself when the field is missing.
+func scalar(n *yaml.Node, key string) (string, *yaml.Node) {
+	if f := field(n, key); f != nil && f.Kind == yaml.ScalarNode {
+		return f.Value, f
+	}
+	return "", n
+}
+
+// unknownFields reports the mapping keys of n that are not fields of t.
+func (v *validator) unknownFields(n *yaml.Node, t reflect.Type) {
+	switch t.Kind() {
+	case reflect.Slice:
+		for _, item := range items(n) {
+			v.unknownFields(item, t.Elem())
+		}
+	case reflect.Map:
+		if n != nil && n.Kind == yaml.MappingNode {
+			for i := 1; i < len(n.Content); i += 2 {
+				v.unknownFields(n.Content[i], t.Elem())
+			}
+		}
+	case reflect.Struct:
+		if n == nil || n.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i]
+			f, ok := yamlField(t, key.Value)
+			if !ok {
+				v.errorf(key, "unknown field %q", key.Value)
+				continue
+			}
+			v.unknownFields(n.Content[i+1], f.Type)
+		}
+	}
+}
+
+// yamlField returns the field of struct type t that key decodes into.
+func yamlField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if name, _, _ := strings.Cut(f.Tag.Get("yaml"), ","); f.IsExported() && name == key {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// config validates the server settings and services of a file. Secrets must
+// have been read from every file first, as tools may use secrets defined in
+// other files.
+func (v *validator) config(root *yaml.Node) {
+	if root == nil || root.Kind != yaml.MappingNode {
+		return
+	}
+	v.server(field(root, "server"))
+
+	for _, svc := range items(field(root, "services")) {
+		name, at := scalar(svc, "name")
+		if name == "" {
+			v.errorf(at, "service without a name")
+		} else {
+			v.define("service", name, at)
+		}
+		v.service(svc)
+	}
+}
+
+func (v *validator) server(n *yaml.Node) {
+	if n == nil {
+		return
+	}
+	if transport, at := scalar(n, "transport"); transport != "" && transport != "http" && transport != "stdio" {
+		v.errorf(at, "server.transport %q is invalid: expected http or stdio", transport)
+	}
+	if level, at := scalar(n, "log_level"); level != "" {
+		if _, err := logrus.ParseLevel(level); err != nil {
+			v.errorf(at, "server.log_level %q is invalid: expected debug, info, warn or error", level)
+		}
+	}
+	switch provider, at := scalar(field(n, "llm"), "provider"); provider {
+	case "", "openai", "sampling":
+	default:
+		v.errorf(at, "server.llm.provider %q is invalid: expected openai or sampling", provider)
+	}
+	timeouts := field(n, "timeouts")
+	for _, key := range []string{"tool", "resource", "completion", "session"} {
+		value, at := scalar(timeouts, key)
+		if d, err := time.ParseDuration(value); err == nil && d < 0 {
+			v.errorf(at, "server.timeouts.%s must not be negative", key)
+		}
+	}
+	if origins := field(n, "allowed_origins"); origins != nil && origins.Kind == yaml.SequenceNode {
+		for _, item := range origins.Content {
+			if u, err := url.Parse(item.Value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+				v.errorf(item, "server.allowed_origins %q is invalid: expected an origin such as https://app.example.com", item.Value)
+			}
+		}
+	}
+}
+
+// secretsSection reads the value of every secret of a file. Relative secret
+// files are read from the directory of the configuration file.
+func (v *validator) secretsSection(n *yaml.Node) {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		name, secret := n.Content[i].Value, n.Content[i+1]
+		v.define("secret", name, n.Content[i])
+		// Undefined and unreadable secrets are told apart, so tools using a
+		// secret that failed to resolve are not reported as well.
+		v.secrets[name] = ""
+
+		env, fromEnv := scalar(secret, "env")
+		file, fromFile := scalar(secret, "file")
+		value, fromValue := scalar(secret, "value")
+		sources := 0
+		for _, from := range []*yaml.Node{fromEnv, fromFile, fromValue} {
+			if from != secret {
+				sources++
+			}
+		}
+		if sources != 1 {
+			v.errorf(secret, "secret %s must set exactly one of env, file or value", name)
+			continue
+		}
+
+		switch {
+		case fromEnv != secret:
+			var ok bool
+			if value, ok = os.LookupEnv(env); !ok {
+				v.errorf(fromEnv, "secret %s: environment variable %s is not set", name, env)
+			}
+		case fromFile != secret:
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(filepath.Dir(v.file), file)
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				v.errorf(fromFile, "secret %s: %v", name, err)
+			}
+			value = strings.TrimRight(string(data), "\r\n")
+		}
+		v.secrets[name] = value
+	}
+}
+
+func (v *validator) service(svc *yaml.Node) {
+	servicePlugin, _ := scalar(svc, "plugin")
+	for _, tool := range items(field(svc, "tools")) {
+		name, at := scalar(tool, "name")
+		if name == "" {
+			v.errorf(at, "tool without a name")
+		} else {
+			v.define("tool", name, at)
+		}
+		if plugin, at := scalar(tool, "plugin"); plugin == "" && servicePlugin == "" {
+			v.errorf(at, "tool %s must set plugin", name)
+		}
+		if handler, at := scalar(tool, "handler"); handler != "" && !handlerPattern.MatchString(handler) {
+			v.errorf(at, "tool %s handler %q must name an exported function, e.g. CreateNetwork or plug.CreateNetwork", name, handler)
+		}
+		for _, key := range []string{"timeout", "retry_backoff"} {
+			value, at := scalar(tool, key)
+			if d, err := time.ParseDuration(value); err == nil && d < 0 {
+				v.errorf(at, "tool %s %s must not be negative", name, key)
+			}
+		}
+		for _, key := range []string{"retries", "max_concurrency"} {
+			value, at := scalar(tool, key)
+			if n, err := strconv.Atoi(value); err == nil && n < 0 {
+				v.errorf(at, "tool %s %s must not be negative", name, key)
+			}
+		}
+		for _, secret := range items(field(tool, "secrets")) {
+			if _, ok := v.secrets[secret.Value]; !ok {
+				v.errorf(secret, "tool %s uses undefined secret %s", name, secret.Value)
+			}
+		}
+		if schema := field(tool, "schema"); schema != nil {
+			v.objectSchema(schema, fmt.Sprintf("tool %s schema", name))
+		}
+	}
+
+	for _, res := range items(field(svc, "resources")) {
+		name, at := scalar(res, "name")
+		if name == "" {
+			v.errorf(at, "resource without a name")
+		}
+		if uri, at := scalar(res, "uri"); uri == "" {
+			v.errorf(at, "resource %s must set uri", name)
+		} else {
+			v.define("resource", uri, at)
+		}
+		path, _ := scalar(res, "path")
+		plugin, _ := scalar(res, "plugin")
+		if (path == "") == (plugin == "") {
+			v.errorf(at, "resource %s must set either path or plugin", name)
+		}
+		if schema := field(res, "schema"); schema != nil {
+			v.objectSchema(schema, fmt.Sprintf("resource %s schema", name))
+		}
+	}
+
+	for _, p := range items(field(svc, "prompts")) {
+		name, at := scalar(p, "name")
+		if name == "" {
+			v.errorf(at, "prompt without a name")
+		} else {
+			v.define("prompt", name, at)
+		}
+		v.prompt(p, name)
+	}
+}
+
+func (v *validator) prompt(p *yaml.Node, name string) {
+	for _, arg := range items(field(p, "arguments")) {
+		argName, at := scalar(arg, "name")
+		if argName == "" {
+			v.errorf(at, "prompt %s has an argument without a name", name)
+		}
+		if schema := field(arg, "schema"); schema != nil {
+			v.schema(schema, fmt.Sprintf("prompt %s argument %s schema", name, argName))
+		}
+	}
+
+	tmpl, at := scalar(p, "template")
+	messages := items(field(p, "messages"))
+	if tmpl == "" && len(messages) == 0 {
+		v.errorf(p, "prompt %s must define a template or messages", name)
+	}
+	if tmpl != "" {
+		v.template(at, name, tmpl)
+	}
+	for _, msg := range messages {
+		if role, at := scalar(msg, "role"); role != "user" && role != "assistant" {
+			v.errorf(at, "prompt %s message has invalid role %q: expected user or assistant", name, role)
+		}
+		if tmpl, at := scalar(msg, "template"); tmpl != "" {
+			v.template(at, name, tmpl)
+		}
+	}
+}
+
+func (v *validator) template(n *yaml.Node, name, text string) {
+	if _, err := template.New(name).Parse(text); err != nil {
+		v.errorf(n, "failed to parse template of prompt %s: %v", name, err)
+	}
+}
+
+// schemaTypes are the type names defined by JSON Schema.
+var schemaTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true,
+	"number": true, "integer": true, "string": true,
+}
+
+// schemaKeyword describes the value a JSON Schema keyword takes.
+type schemaKeyword int
+
+const (
+	keywordAny       schemaKeyword = iota // any value
+	keywordString                         // a string
+	keywordBool                           // a boolean
+	keywordNumber                         // a number
+	keywordCount                          // a non-negative integer
+	keywordSchema                         // a schema
+	keywordSchemas                        // a non-empty list of schemas
+	keywordSchemaMap                      // a mapping of names to schemas
+	keywordItems                          // a schema or a list of schemas
+	keywordType                           // a type name or a list of type names
+	keywordRequired                       // a list of unique property names
+	keywordEnum                           // a non-empty list of values
+	keywordPattern                        // a regular expression
+)
+
+// schemaKeywords are the JSON Schema keywords accepted in schemas.
+var schemaKeywords = map[string]schemaKeyword{
+	"$schema": keywordString, "$id": keywordString, "$ref": keywordString,
+	"$anchor": keywordString, "$comment": keywordString,
+	"title": keywordString, "description": keywordString, "format": keywordString,
+	"contentEncoding": keywordString, "contentMediaType": keywordString,
+	"default": keywordAny, "examples": keywordAny, "const": keywordAny,
+	"dependencies": keywordAny, "dependentRequired": keywordAny,
+	"readOnly": keywordBool, "writeOnly": keywordBool, "deprecated": keywordBool, "uniqueItems": keywordBool,
+	"multipleOf": keywordNumber, "minimum": keywordNumber, "maximum": keywordNumber,
+	"exclusiveMinimum": keywordNumber, "exclusiveMaximum": keywordNumber,
+	"minLength": keywordCount, "maxLength": keywordCount, "minItems": keywordCount, "maxItems": keywordCount,
+	"minProperties": keywordCount, "maxProperties": keywordCount, "minContains": keywordCount, "maxContains": keywordCount,
+	"additionalProperties": keywordSchema, "unevaluatedProperties": keywordSchema, "propertyNames": keywordSchema,
+	"additionalItems": keywordSchema, "unevaluatedItems": keywordSchema, "contains": keywordSchema,
+	"not": keywordSchema, "if": keywordSchema, "then": keywordSchema, "else": keywordSchema,
+	"allOf": keywordSchemas, "anyOf": keywordSchemas, "oneOf": keywordSchemas, "prefixItems": keywordSchemas,
+	"properties": keywordSchemaMap, "patternProperties": keywordSchemaMap, "dependentSchemas": keywordSchemaMap,
+	"definitions": keywordSchemaMap, "$defs": keywordSchemaMap,
+	"items":    keywordItems,
+	"type":     keywordType,
+	"required": keywordRequired,
+	"enum":     keywordEnum,
+	"pattern":  keywordPattern,
+}
+
+// objectSchema validates the schema of tool arguments or template variables,
+// which must describe an object.
+func (v *validator) objectSchema(n *yaml.Node, where string) {
+	if n.Kind != yaml.MappingNode {
+		v.errorf(n, "%s must be a mapping", where)
+		return
+	}
+	if typ, at := scalar(n, "type"); typ != "object" {
+		v.errorf(at, "%s must have type object", where)
+	}
+	v.schema(n, where)
+}
+
+// validatePluginSchema checks the schema a plugin provides for a tool with the
+// rules applied to schemas in configuration files. The errors name the plugin
+// in place of a file and carry no line.
+func validatePluginSchema(plugin, tool string, schema map[string]interface{}) error {
+	if schema == nil {
+		return nil
+	}
+	// Schemas hold JSON values, so their JSON form decodes as YAML with the
+	// tags the checks expect.
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return fmt.Errorf("invalid schema of tool %s of plugin %s: %v", tool, plugin, err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return fmt.Errorf("invalid schema of tool %s of plugin %s: %v", tool, plugin, err)
+	}
+	v := newValidator()
+	v.file = plugin
+	v.objectSchema(doc.Content[0], fmt.Sprintf("tool %s schema", tool))
+	for _, e := range v.errs {
+		e.Line = 0
+	}
+	return v.result()
+}
+
+// schema validates n as a JSON Schema document. where names the schema in
+// error messages and is extended with the path of nested schemas.
+func (v *validator) schema(n *yaml.Node, where string) {
+	if isTag(n, "!!bool") {
+		return
+	}
+	if n.Kind != yaml.MappingNode {
+		v.errorf(n, "%s must be a schema mapping or a boolean", where)
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i].Value, n.Content[i+1]
+		at := where + "." + key
+		kind, ok := schemaKeywords[key]
+		if !ok {
+			if !strings.HasPrefix(key, "x-") {
+				v.errorf(n.Content[i], "%s: unknown JSON Schema keyword %q", where, key)
+			}
+			continue
+		}
+		switch kind {
+		case keywordString:
+			if !isTag(value, "!!str") {
+				v.errorf(value, "%s must be a string", at)
+			}
+		case keywordBool:
+			if !isTag(value, "!!bool") {
+				v.errorf(value, "%s must be a boolean", at)
+			}
+		case keywordNumber:
+			if !isTag(value, "!!int") && !isTag(value, "!!float") {
+				v.errorf(value, "%s must be a number", at)
+			}
+		case keywordCount:
+			if count, err := strconv.Atoi(value.Value); !isTag(value, "!!int") || err != nil || count < 0 {
+				v.errorf(value, "%s must be a non-negative integer", at)
+			}
+		case keywordSchema:
+			v.schema(value, at)
+		case keywordSchemas:
+			if value.Kind != yaml.SequenceNode || len(value.Content) == 0 {
+				v.errorf(value, "%s must be a non-empty list of schemas", at)
+				continue
+			}
+			for j, item := range value.Content {
+				v.schema(item, fmt.Sprintf("%s[%d]", at, j))
+			}
+		case keywordSchemaMap:
+			if value.Kind != yaml.MappingNode {
+				v.errorf(value, "%s must be a mapping of schemas", at)
+				continue
+			}
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				name := value.Content[j].Value
+				if key == "patternProperties" {
+					if _, err := regexp.Compile(name); err != nil {
+						v.errorf(value.Content[j], "%s: invalid pattern %q: %v", at, name, err)
+					}
+				}
+				v.schema(value.Content[j+1], at+"."+name)
+			}
+		case keywordItems:
+			if value.Kind == yaml.SequenceNode {
+				for j, item := range value.Content {
+					v.schema(item, fmt.Sprintf("%s[%d]", at, j))
+				}
+			} else {
+				v.schema(value, at)
+			}
+		case keywordType:
+			v.schemaType(value, at)
+		case keywordRequired:
+			v.schemaRequired(value, field(n, "properties"), at)
+		case keywordEnum:
+			if value.Kind != yaml.SequenceNode || len(value.Content) == 0 {
+				v.errorf(value, "%s must be a non-empty list", at)
+			}
+		case keywordPattern:
+			if _, err := regexp.Compile(value.Value); !isTag(value, "!!str") || err != nil {
+				v.errorf(value, "%s must be a valid regular expression", at)
+			}
+		}
+	}
+}
+
+// schemaType validates the value of a type keyword.
+func (v *validator) schemaType(n *yaml.Node, at string) {
+	names := []*yaml.Node{n}
+	if n.Kind == yaml.SequenceNode {
+		if len(n.Content) == 0 {
+			v.errorf(n, "%s must not be empty", at)
+		}
+		names = n.Content
+	}
+	for _, name := range names {
+		if !isTag(name, "!!str") || !schemaTypes[name.Value] {
+			v.errorf(name, "%s %q is not a JSON Schema type: expected one of null, boolean, object, array, number, integer or string", at, name.Value)
+		}
+	}
+}
+
+// schemaRequired validates the value of a required keyword against the
+// properties defined next to it.
+func (v *validator) schemaRequired(n, properties *yaml.Node, at string) {
+	if n.Kind != yaml.SequenceNode {
+		v.errorf(n, "%s must be a list of property names", at)
+		return
+	}
+	seen := make(map[string]bool)
+	for _, name := range n.Content {
+		switch {
+		case !isTag(name, "!!str"):
+			v.errorf(name, "%s must be a list of property names", at)
+		case seen[name.Value]:
+			v.errorf(name, "%s lists %q more than once", at, name.Value)
+		case properties != nil && properties.Kind == yaml.MappingNode && field(properties, name.Value) == nil:
+			v.errorf(name, "%s lists %q, which is not defined in properties", at, name.Value)
+		}
+		seen[name.Value] = true
+	}
+}
+
+// isTag reports whether n is a scalar with the given resolved tag.
+func isTag(n *yaml.Node, tag string) bool {
+	return n.Kind == yaml.ScalarNode && n.ShortTag() == tag
+}
@@ -0,0 +1,248 @@
+package reg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes a configuration file under dir and returns its path.
+func writeConfig(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// configErrors loads the configuration at path and returns its errors, with
+// file names, including those in messages, relative to dir.
+func configErrors(t *testing.T, dir, path string) []string {
+	t.Helper()
+	_, err := loadConfig(path)
+	if err == nil {
+		return nil
+	}
+	var errs ConfigErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("loadConfig(%s) returned %T, want ConfigErrors: %v", path, err, err)
+	}
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		rel, err := filepath.Rel(dir, e.File)
+		if err != nil {
+			rel = e.File
+		}
+		msg := strings.ReplaceAll(e.Message, dir+string(filepath.Separator), "")
+		msgs[i] = (&ConfigError{File: filepath.ToSlash(rel), Line: e.Line, Message: msg}).Error()
+	}
+	return msgs
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{
+			name: "valid",
+			config: `
+server:
+  transport: stdio
+  timeouts:
+    tool: 10s
+services:
+  - name: Docker
+    enabled: true
+    tools:
+      - name: create_network
+        plugin: example.com/plug
+        handler: plug.CreateNetwork
+        schema:
+          type: object
+          properties:
+            name: {type: string, minLength: 1}
+          required: [name]
+`,
+		},
+		{
+			name: "unknown fields",
+			config: `
+server:
+  adress: ":1234"
+services:
+  - name: Docker
+    enabeld: true
+    tools:
+      - name: create_network
+        plugin: example.com/plug
+        retry: 2
+`,
+			want: []string{
+				`a.yaml:3: unknown field "adress"`,
+				`a.yaml:6: unknown field "enabeld"`,
+				`a.yaml:10: unknown field "retry"`,
+			},
+		},
+		{
+			name: "type errors",
+			config: `
+server:
+  timeouts:
+    tool: soon
+services:
+  - name: Docker
+    enabled: maybe
+    tools:
+      - name: create_network
+        plugin: example.com/plug
+        retries: many
+`,
+			want: []string{
+				"a.yaml:4: cannot unmarshal !!str `soon` into time.Duration",
+				"a.yaml:7: cannot unmarshal !!str `maybe` into bool",
+				"a.yaml:11: cannot unmarshal !!str `many` into int",
+			},
+		},
+		{
+			name: "invalid settings",
+			config: `
+server:
+  transport: grpc
+  log_level: loud
+  allowed_origins: ["https://app.example.com", "app.example.com"]
+  timeouts:
+    session: -1m
+services:
+  - name: Docker
+    tools:
+      - name: create_network
+        retries: -1
+`,
+			want: []string{
+				`a.yaml:3: server.transport "grpc" is invalid: expected http or stdio`,
+				`a.yaml:4: server.log_level "loud" is invalid: expected debug, info, warn or error`,
+				`a.yaml:5: server.allowed_origins "app.example.com" is invalid: expected an origin such as https://app.example.com`,
+				"a.yaml:7: server.timeouts.session must not be negative",
+				"a.yaml:11: tool create_network must set plugin",
+				"a.yaml:12: tool create_network retries must not be negative",
+			},
+		},
+		{
+			name: "duplicate tools across services",
+			config: `
+services:
+  - name: Docker
+    tools:
+      - name: create_network
+        plugin: example.com/plug
+  - name: Podman
+    tools:
+      - name: create_network
+        plugin: example.com/pod
+  - name: Docker
+`,
+			want: []string{
+				"a.yaml:9: duplicate tool create_network, first defined at a.yaml:5",
+				"a.yaml:11: duplicate service Docker, first defined at a.yaml:3",
+			},
+		},
+		{
+			name: "schema keywords",
+			config: `
+services:
+  - name: Docker
+    tools:
+      - name: create_network
+        plugin: example.com/plug
+        schema:
+          type: array
+          properties:
+            name:
+              type: text
+              minLength: -1
+              maxLength: 10
+              pattern: "("
+              x-ui: wide
+            driver:
+              enum: []
+              default: bridge
+          required: [name, name, labels]
+          additionalProperties: maybe
+          colour: red
+`,
+			want: []string{
+				"a.yaml:8: tool create_network schema must have type object",
+				`a.yaml:11: tool create_network schema.properties.name.type "text" is not a JSON Schema type: expected one of null, boolean, object, array, number, integer or string`,
+				"a.yaml:12: tool create_network schema.properties.name.minLength must be a non-negative integer",
+				"a.yaml:14: tool create_network schema.properties.name.pattern must be a valid regular expression",
+				"a.yaml:17: tool create_network schema.properties.driver.enum must be a non-empty list",
+				`a.yaml:19: tool create_network schema.required lists "name" more than once`,
+				`a.yaml:19: tool create_network schema.required lists "labels", which is not defined in properties`,
+				"a.yaml:20: tool create_network schema.additionalProperties must be a schema mapping or a boolean",
+				`a.yaml:21: tool create_network schema: unknown JSON Schema keyword "colour"`,
+			},
+		},
+		{
+			name: "handler pattern",
+			config: `
+services:
+  - name: Docker
+    tools:
+      - name: a
+        plugin: example.com/plug
+        handler: CreateNetwork
+      - name: b
+        plugin: example.com/plug
+        handler: plug.CreateNetwork
+      - name: c
+        plugin: example.com/plug
+        handler: createNetwork
+      - name: d
+        plugin: example.com/plug
+        handler: plug.createNetwork
+      - name: e
+        plugin: example.com/plug
+        handler: go-plug.CreateNetwork
+      - name: f
+        plugin: example.com/plug
+        handler: plug.Create.Network
+`,
+			want: []string{
+				`a.yaml:13: tool c handler "createNetwork" must name an exported function, e.g. CreateNetwork or plug.CreateNetwork`,
+				`a.yaml:16: tool d handler "plug.createNetwork" must name an exported function, e.g. CreateNetwork or plug.CreateNetwork`,
+				`a.yaml:19: tool e handler "go-plug.CreateNetwork" must name an exported function, e.g. CreateNetwork or plug.CreateNetwork`,
+				`a.yaml:22: tool f handler "plug.Create.Network" must name an exported function, e.g. CreateNetwork or plug.CreateNetwork`,
+			},
+		},
+		{
+			name: "syntax error",
+			config: `
+services:
+  - name: Docker
+   tools: []
+`,
+			want: []string{
+				"a.yaml:2: did not find expected '-' indicator",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := writeConfig(t, dir, "a.yaml", tt.config)
+			if got := configErrors(t, dir, path); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadConfig errors:\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/santoshkal/gomcp/pkg/mcp"
+	"github.com/santoshkal/gomcp/pkg/plugins"
+)
+
+// maxCompletionValues is the largest number of values returned by completion/complete.
+const maxCompletionValues = 100
+
+// completionSource holds the completions of a prompt or resource template.
+type completionSource struct {
+	values    map[string][]string // static values keyed by argument
+	completer plugins.Completer
+}
+
+// Ensure Server implements mcp.CompletionRegistry.
+var _ mcp.CompletionRegistry = (*Server)(nil)
+
+// RegisterCompletionValues implements the mcp.CompletionRegistry interface.
+func (s *Server) RegisterCompletionValues(ref mcp.CompletionRef, argument string, values []string) {
+	s.completionsMu.Lock()
+	defer s.completionsMu.Unlock()
+	s.addCompletion(mcp.CompletionRegistration{Ref: ref, Argument: argument, Values: values})
+}
+
+// RegisterCompleter implements the mcp.CompletionRegistry interface.
+func (s *Server) RegisterCompleter(ref mcp.CompletionRef, completer plugins.Completer) {
+	s.completionsMu.Lock()
+	defer s.completionsMu.Unlock()
+	s.addCompletion(mcp.CompletionRegistration{Ref: ref, Completer: completer})
+}
+
+// addCompletion stores the values or completer of c. s.completionsMu must be held.
+func (s *Server) addCompletion(c mcp.CompletionRegistration) {
+	src, ok := s.completions[c.Ref.Key()]
+	if !ok {
+		src = &completionSource{values: make(map[string][]string)}
+		s.completions[c.Ref.Key()] = src
+	}
+	if c.Completer != nil {
+		logger.Debugf("Registering completer for %s", c.Ref.Key())
+		src.completer = c.Completer
+		return
+	}
+	logger.Debugf("Registering %d completion values for %s argument %s", len(c.Values), c.Ref.Key(), c.Argument)
+	src.values[c.Argument] = c.Values
+}
+
+// UnregisterCompletions implements the mcp.CompletionRegistry interface.
+func (s *Server) UnregisterCompletions(ref mcp.CompletionRef) {
+	s.completionsMu.Lock()
+	defer s.completionsMu.Unlock()
+	delete(s.completions, ref.Key())
+}
+
+// handleCompletionComplete suggests values for an argument of a prompt or
+// resource template. Static values are filtered by prefix; completers receive
+// the partial value and filter themselves.
+func (s *Server) handleCompletionComplete(ctx context.Context, sess *Session, params json.RawMessage) (interface{}, *mcp.RPCError) {
+	var p mcp.CompleteParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, mcp.NewError(mcp.InvalidParams, fmt.Sprintf("invalid completion/complete params: %v", err))
+	}
+	if p.Argument.Name == "" {
+		return nil, mcp.NewError(mcp.InvalidParams, "completion/complete requires an argument name")
+	}
+
+	switch p.Ref.Type {
+	case mcp.RefPrompt:
+		if _, exists := s.prompt(p.Ref.Name); !exists {
+			return nil, mcp.NewError(mcp.InvalidParams, fmt.Sprintf("unknown prompt: %s", p.Ref.Name))
+		}
+	case mcp.RefResource:
+		s.resourcesMu.RLock()
+		res, exists := s.resources[p.Ref.URI]
+		s.resourcesMu.RUnlock()
+		if !exists || !res.Template {
+			return nil, mcp.NewError(mcp.InvalidParams, fmt.Sprintf("unknown resource template: %s", p.Ref.URI))
+		}
+	default:
+		return nil, mcp.NewError(mcp.InvalidParams, fmt.Sprintf("invalid completion reference type: %q", p.Ref.Type))
+	}
+
+	var values []string
+	var completer plugins.Completer
+	s.completionsMu.RLock()
+	if src, ok := s.completions[p.Ref.Key()]; ok {
+		values = src.values[p.Argument.Name]
+		completer = src.completer
+	}
+	s.completionsMu.RUnlock()
+
+	prefix := strings.ToLower(p.Argument.Value)
+	seen := make(map[string]bool)
+	var matches []string
+	for _, v := range values {
+		if strings.HasPrefix(strings.ToLower(v), prefix) && !seen[v] {
+			seen[v] = true
+			matches = append(matches, v)
+		}
+	}
+	sort.Strings(matches)
+
+	if completer != nil {
+		ctx, cancel := context.WithTimeout(ctx, s.timeouts().Completion)
+		defer cancel()
+		ctx = withLogger(ctx, sess, p.Ref.Key())
+		suggested, err := completer(ctx, p.Argument.Name, p.Argument.Value)
+		if err != nil {
+			logger.Errorf("[completion/complete] Completer for %s failed: %v", p.Ref.Key(), err)
+			return nil, mcp.NewError(mcp.InternalError, fmt.Sprintf("completion failed: %v", err))
+		}
+		for _, v := range suggested {
+			if !seen[v] {
+				seen[v] = true
+				matches = append(matches, v)
+			}
+		}
+	}
+
+	completion := mcp.Completion{Values: matches, Total: len(matches)}
+	if completion.Values == nil {
+		completion.Values = []string{}
+	}
+	if len(matches) > maxCompletionValues {
+		completion.Values = matches[:maxCompletionValues]
+		completion.HasMore = true
+	}
+	return mcp.CompleteResult{Completion: completion}, nil
+}
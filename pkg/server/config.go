@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/santoshkal/gomcp/pkg/mcp"
+	"github.com/santoshkal/gomcp/pkg/reg"
+)
+
+// methodReloadConfig is the admin method that reloads the configuration file.
+const methodReloadConfig = "admin/reloadConfig"
+
+// configPollInterval is how often the configuration file is checked for changes.
+const configPollInterval = 2 * time.Second
+
+// ReloadConfig re-reads the configuration file and applies the services and
+// tools that changed. On error the running configuration is kept.
+func (s *Server) ReloadConfig() (*reg.ReloadResult, error) {
+	logger.Debugf("Entering ReloadConfig for %s", s.config.Path())
+	defer logger.Debug("Exiting ReloadConfig")
+
+	result, err := s.config.Reload(s)
+	if err != nil {
+		logger.Errorf("failed to reload config %s, keeping the running configuration: %v", s.config.Path(), err)
+		return nil, err
+	}
+	s.applySettings(s.config.Settings())
+	logger.Infof("Reloaded config %s: %v", s.config.Path(), result)
+	return result, nil
+}
+
+// Settings returns the server settings in effect.
+func (s *Server) Settings() reg.ServerConfig {
+	s.settingsMu.RLock()
+	defer s.settingsMu.RUnlock()
+	return s.settings
+}
+
+// timeouts returns the configured handler timeouts.
+func (s *Server) timeouts() reg.TimeoutConfig {
+	return s.Settings().Timeouts
+}
+
+// applySettings puts the log level and timeouts of settings into effect. The
+// address, transport and LLM are fixed at startup, so changes to them are
+// reported and ignored.
+func (s *Server) applySettings(settings reg.ServerConfig) {
+	if level, err := logrus.ParseLevel(settings.LogLevel); err != nil {
+		logger.Warnf("ignoring invalid server.log_level %q: %v", settings.LogLevel, err)
+	} else {
+		logger.SetLevel(level)
+	}
+
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+	if prev := s.settings; prev.Address != "" {
+		if prev.Address != settings.Address || prev.Transport != settings.Transport || prev.LLM != settings.LLM {
+			logger.Warnf("server address, transport and llm settings take effect after a restart")
+		}
+		settings.Address, settings.Transport, settings.LLM = prev.Address, prev.Transport, prev.LLM
+	}
+	s.settings = settings
+}
+
+// watchConfig polls the configuration and the files it includes, and reloads
+// it when one of them changes.
+func (s *Server) watchConfig() {
+	last := s.configStamp()
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		current := s.configStamp()
+		if current == last || current == "" {
+			continue
+		}
+		logger.Infof("Config %s changed, reloading", s.config.Path())
+		s.ReloadConfig()
+		// The reload may have added or removed included files.
+		last = s.configStamp()
+	}
+}
+
+// configStamp identifies the state of the configuration path and of every file
+// and directory it was read from. A directory's stamp changes when files are
+// added or removed.
+// It is empty when the configuration path does not exist.
+func (s *Server) configStamp() string {
+	stamp := fileStamp(s.config.Path())
+	if stamp == "" {
+		return ""
+	}
+	for _, file := range s.config.Files() {
+		stamp += "," + fileStamp(file)
+	}
+	return stamp
+}
+
+// handleReloadConfig serves admin/reloadConfig and returns a summary of the changes.
+func (s *Server) handleReloadConfig(ctx context.Context, sess *Session, params json.RawMessage) (interface{}, *mcp.RPCError) {
+	result, err := s.ReloadConfig()
+	if err != nil {
+		return nil, mcp.NewError(mcp.ServerError, fmt.Sprintf("failed to reload config: %v", err))
+	}
+	return result, nil
+}
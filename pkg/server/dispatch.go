@@ -0,0 +1,236 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/santoshkal/gomcp/pkg/mcp"
+)
+
+// methodHandler handles a single MCP request or notification within a session.
+type methodHandler func(ctx context.Context, sess *Session, params json.RawMessage) (interface{}, *mcp.RPCError)
+
+// mcpMethods returns the methods served by s, keyed by method name.
+func (s *Server) mcpMethods() map[string]methodHandler {
+	methods := map[string]methodHandler{
+		mcp.MethodInitialize:        s.handleInitialize,
+		mcp.MethodPing:              s.handlePing,
+		mcp.NotificationInitialized: s.handleInitialized,
+		mcp.NotificationCancelled:   s.handleCancelled,
+		mcp.MethodToolsList:         s.handleToolsList,
+		mcp.MethodToolsCall:         s.handleToolsCall,
+
+		mcp.MethodResourcesList:         s.handleResourcesList,
+		mcp.MethodResourceTemplatesList: s.handleResourceTemplatesList,
+		mcp.MethodResourcesRead:         s.handleResourcesRead,
+		mcp.MethodResourcesSubscribe:    s.handleResourcesSubscribe,
+		mcp.MethodResourcesUnsubscribe:  s.handleResourcesUnsubscribe,
+
+		mcp.MethodLoggingSetLevel: s.handleLoggingSetLevel,
+
+		mcp.MethodPromptsList: s.handlePromptsList,
+		mcp.MethodPromptsGet:  s.handlePromptsGet,
+
+		mcp.MethodCompletionComplete: s.handleCompletionComplete,
+
+		mcp.NotificationRootsListChanged: s.handleRootsListChanged,
+
+		methodReloadConfig: s.handleReloadConfig,
+	}
+	for name, handler := range s.legacyMethods() {
+		methods[name] = handler
+	}
+	return methods
+}
+
+// allowedBeforeInitialize reports whether method may be called before initialize.
+func allowedBeforeInitialize(method string) bool {
+	return method == mcp.MethodInitialize || method == mcp.MethodPing || method == methodReloadConfig || isLegacyMethod(method)
+}
+
+// handleMCP dispatches a validated request within sess. It returns nil for notifications.
+func (s *Server) handleMCP(ctx context.Context, sess *Session, req *mcp.RPCRequest) *mcp.RPCResponse {
+	logger.Debugf("Entering handleMCP for method: %s", req.Method)
+	defer logger.Debug("Exiting handleMCP")
+
+	response := &mcp.RPCResponse{Version: mcp.JSONRPCVersion, ID: req.ID}
+
+	handler, exists := s.methods[req.Method]
+	if !exists {
+		response.Error = mcp.NewError(mcp.MethodNotFound, fmt.Sprintf("method not found: %s", req.Method))
+		return replyUnlessNotification(req, response)
+	}
+	if !sess.ready() && !allowedBeforeInitialize(req.Method) {
+		response.Error = mcp.NewError(mcp.InvalidRequest, fmt.Sprintf("session not initialized: %s requires initialize first", req.Method))
+		return replyUnlessNotification(req, response)
+	}
+
+	// Track requests so notifications/cancelled can stop them. The handshake
+	// itself cannot be cancelled. Only requests wait for the client's roots:
+	// notifications and the handshake are handled by the stdio read loop,
+	// which must keep reading to receive the roots/list reply.
+	var inflight *inflightRequest
+	if !req.IsNotification() && req.Method != mcp.MethodInitialize {
+		var done func()
+		ctx, inflight, done = sess.track(ctx, req.ID)
+		defer done()
+		ctx = s.withRoots(ctx, sess)
+	}
+
+	ctx = withSampler(ctx, sess)
+	result, rpcErr := handler(ctx, sess, req.Params)
+	if inflight != nil && sess.wasCancelled(inflight) {
+		logger.Infof("Request %s (%s) was cancelled by the client", req.ID, req.Method)
+		response.Error = mcp.NewError(mcp.RequestCancelled, fmt.Sprintf("request cancelled: %s", req.Method))
+		return response
+	}
+	if rpcErr != nil {
+		response.Error = rpcErr
+		return replyUnlessNotification(req, response)
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		response.Error = mcp.NewError(mcp.InternalError, fmt.Sprintf("failed to marshal result: %v", err))
+	} else {
+		response.Result = json.RawMessage(resultJSON)
+	}
+	return replyUnlessNotification(req, response)
+}
+
+// handleCancelled cancels the in-flight request named by the notification.
+func (s *Server) handleCancelled(ctx context.Context, sess *Session, params json.RawMessage) (interface{}, *mcp.RPCError) {
+	var p mcp.CancelledParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, mcp.NewError(mcp.InvalidParams, fmt.Sprintf("invalid notifications/cancelled params: %v", err))
+	}
+	if sess.cancelRequest(p.RequestID) {
+		logger.Debugf("Cancelling request %s: %s", p.RequestID, p.Reason)
+	} else {
+		logger.Debugf("Ignoring cancellation of unknown or completed request %s", p.RequestID)
+	}
+	return nil, nil
+}
+
+// replyUnlessNotification drops the response when req carries no ID.
+func replyUnlessNotification(req *mcp.RPCRequest, response *mcp.RPCResponse) *mcp.RPCResponse {
+	if req.IsNotification() {
+		return nil
+	}
+	return response
+}
+
+// inboundMessage is any message a client may send: a request, a notification,
+// or a response to a request made by the server.
+type inboundMessage struct {
+	mcp.RPCRequest
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *mcp.RPCError   `json:"error,omitempty"`
+}
+
+// isResponse reports whether the message answers a server-initiated request.
+func (m *inboundMessage) isResponse() bool {
+	return m.Method == "" && (len(m.Result) > 0 || m.Error != nil)
+}
+
+// handleRaw validates and dispatches a single message of a payload.
+func (s *Server) handleRaw(ctx context.Context, sess *Session, raw json.RawMessage) *mcp.RPCResponse {
+	var msg inboundMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return &mcp.RPCResponse{
+			Version: mcp.JSONRPCVersion,
+			Error:   mcp.NewError(mcp.InvalidRequest, fmt.Sprintf("invalid request: %v", err)),
+		}
+	}
+	if msg.isResponse() {
+		if !sess.deliver(&msg) {
+			logger.Debugf("[handleRaw] Ignoring response to unknown request %s", msg.ID)
+		}
+		return nil
+	}
+	if err := msg.Validate(); err != nil {
+		response := &mcp.RPCResponse{
+			Version: mcp.JSONRPCVersion,
+			Error:   mcp.NewError(mcp.InvalidRequest, fmt.Sprintf("invalid request: %v", err)),
+		}
+		if msg.ID.Valid() {
+			response.ID = msg.ID
+		}
+		return response
+	}
+	return s.handleMCP(ctx, sess, &msg.RPCRequest)
+}
+
+// handleMessage decodes a JSON-RPC payload, which may be a single message or a
+// batch, and dispatches it within sess. It returns the reply to encode: a single
+// response, a slice of responses for a batch, or nil when nothing is owed.
+func (s *Server) handleMessage(ctx context.Context, sess *Session, data []byte) interface{} {
+	msgs, batch, err := mcp.DecodeBatch(data)
+	if err != nil {
+		logger.Errorf("[handleMessage] Error unmarshalling request: %v", err)
+		return &mcp.RPCResponse{
+			Version: mcp.JSONRPCVersion,
+			Error:   mcp.NewError(mcp.ParseError, fmt.Sprintf("parse error: %v", err)),
+		}
+	}
+	if !batch {
+		if response := s.handleRaw(ctx, sess, msgs[0]); response != nil {
+			return response
+		}
+		return nil
+	}
+	if len(msgs) == 0 {
+		return &mcp.RPCResponse{
+			Version: mcp.JSONRPCVersion,
+			Error:   mcp.NewError(mcp.InvalidRequest, "invalid request: empty batch"),
+		}
+	}
+
+	var responses []*mcp.RPCResponse
+	for _, raw := range msgs {
+		if response := s.handleRaw(ctx, sess, raw); response != nil {
+			responses = append(responses, response)
+		}
+	}
+	if len(responses) == 0 {
+		return nil
+	}
+	return responses
+}
+
+// expectsReply reports whether dispatching raw produces a response.
+func expectsReply(raw json.RawMessage) bool {
+	var msg inboundMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return true
+	}
+	if msg.isResponse() {
+		return false
+	}
+	return msg.Validate() != nil || !msg.IsNotification()
+}
+
+// expectsReplyPayload reports whether any message of a payload produces a response.
+// Malformed payloads produce a parse error response.
+func expectsReplyPayload(data []byte) bool {
+	msgs, _, err := mcp.DecodeBatch(data)
+	if err != nil || len(msgs) == 0 {
+		return true
+	}
+	for _, raw := range msgs {
+		if expectsReply(raw) {
+			return true
+		}
+	}
+	return false
+}
+
+// isInitializeRequest reports whether raw is an initialize request.
+func isInitializeRequest(raw json.RawMessage) bool {
+	var req mcp.RPCRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return false
+	}
+	return req.Method == mcp.MethodInitialize && !req.IsNotification()
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/santoshkal/gomcp/pkg/mcp"
+)
+
+// legacyPrefix prefixes the methods that were previously served through net/rpc.
+const legacyPrefix = "Server."
+
+// isLegacyMethod reports whether method is one of the net/rpc style methods.
+func isLegacyMethod(method string) bool {
+	return strings.HasPrefix(method, legacyPrefix)
+}
+
+// legacyMethods exposes the original Server.* methods through the JSON-RPC 2.0
+// dispatcher. Their results keep the shape net/rpc clients relied on.
+func (s *Server) legacyMethods() map[string]methodHandler {
+	return map[string]methodHandler{
+		legacyPrefix + "CallTool": func(ctx context.Context, sess *Session, params json.RawMessage) (interface{}, *mcp.RPCError) {
+			var args mcp.ToolCallArgs
+			if err := decodeLegacyParams(params, &args); err != nil {
+				return nil, err
+			}
+			var reply mcp.RPCResponse
+			if err := s.callTool(ctx, &args, &reply); err != nil {
+				return nil, mcp.NewError(mcp.ServerError, err.Error())
+			}
+			return reply, nil
+		},
+		legacyPrefix + "ProcessInstruction": func(ctx context.Context, sess *Session, params json.RawMessage) (interface{}, *mcp.RPCError) {
+			var instruction string
+			if err := decodeLegacyParams(params, &instruction); err != nil {
+				return nil, err
+			}
+			var reply mcp.RPCResponse
+			if err := s.processInstruction(ctx, &instruction, &reply); err != nil {
+				return nil, mcp.NewError(mcp.ServerError, err.Error())
+			}
+			return reply, nil
+		},
+		legacyPrefix + "ExecutePlan": func(ctx context.Context, sess *Session, params json.RawMessage) (interface{}, *mcp.RPCError) {
+			var plan string
+			if err := decodeLegacyParams(params, &plan); err != nil {
+				return nil, err
+			}
+			var reply mcp.RPCResponse
+			if err := s.executePlan(ctx, &plan, &reply); err != nil {
+				return nil, mcp.NewError(mcp.ServerError, err.Error())
+			}
+			return reply, nil
+		},
+		legacyPrefix + "CallLLM": func(ctx context.Context, sess *Session, params json.RawMessage) (interface{}, *mcp.RPCError) {
+			var input string
+			if err := decodeLegacyParams(params, &input); err != nil {
+				return nil, err
+			}
+			var reply string
+			if err := s.callLLM(ctx, &input, &reply); err != nil {
+				return nil, mcp.NewError(mcp.ServerError, err.Error())
+			}
+			return reply, nil
+		},
+	}
+}
+
+// decodeLegacyParams decodes net/rpc style params, which wrap the single
+// argument in a one-element array, as well as the bare argument.
+func decodeLegacyParams(params json.RawMessage, v interface{}) *mcp.RPCError {
+	var wrapped []json.RawMessage
+	if err := json.Unmarshal(params, &wrapped); err == nil {
+		if len(wrapped) != 1 {
+			return mcp.NewError(mcp.InvalidParams, fmt.Sprintf("expected exactly one parameter, got %d", len(wrapped)))
+		}
+		params = wrapped[0]
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return mcp.NewError(mcp.InvalidParams, fmt.Sprintf("invalid params: %v", err))
+	}
+	return nil
+}
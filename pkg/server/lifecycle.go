@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/santoshkal/gomcp/pkg/mcp"
+)
+
+const (
+	serverName    = "gomcp"
+	serverVersion = "0.1.0"
+)
+
+// capabilities returns the advertised server capabilities. Every capability
+// the server supports is advertised, whether or not anything is registered
+// yet, since a reload may register the first tool, resource or prompt later.
+func (s *Server) capabilities() mcp.ServerCapabilities {
+	return mcp.ServerCapabilities{
+		Logging:     &mcp.LoggingCapability{},
+		Tools:       &mcp.ToolsCapability{ListChanged: true},
+		Resources:   &mcp.ResourcesCapability{Subscribe: true, ListChanged: true},
+		Prompts:     &mcp.PromptsCapability{ListChanged: true},
+		Completions: &mcp.CompletionsCapability{},
+	}
+}
+
+// handleInitialize negotiates the protocol version and records the client's capabilities.
+func (s *Server) handleInitialize(ctx context.Context, sess *Session, params json.RawMessage) (interface{}, *mcp.RPCError) {
+	var p mcp.InitializeParams
+	if len(params) == 0 {
+		return nil, mcp.NewError(mcp.InvalidParams, "initialize requires params")
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, mcp.NewError(mcp.InvalidParams, fmt.Sprintf("invalid initialize params: %v", err))
+	}
+
+	// Echo the client's version when supported, otherwise offer our latest.
+	version := p.ProtocolVersion
+	if !mcp.IsSupportedProtocolVersion(version) {
+		logger.Infof("Client requested unsupported protocol version %q, offering %s", version, mcp.LatestProtocolVersion)
+		version = mcp.LatestProtocolVersion
+	}
+
+	sess.mu.Lock()
+	sess.initializing = true
+	sess.protocolVersion = version
+	sess.clientInfo = p.ClientInfo
+	sess.clientCaps = p.Capabilities
+	sess.mu.Unlock()
+
+	// The stateless /rpc endpoint cannot deliver resource updates.
+	caps := s.capabilities()
+	if sess.stateless {
+		caps.Resources.Subscribe = false
+	}
+
+	logger.Infof("Initialized session for client %s %s (protocol %s)", p.ClientInfo.Name, p.ClientInfo.Version, version)
+	return mcp.InitializeResult{
+		ProtocolVersion: version,
+		Capabilities:    caps,
+		ServerInfo:      mcp.Implementation{Name: serverName, Version: serverVersion},
+	}, nil
+}
+
+// handleInitialized marks the session as ready once the client acknowledges the handshake.
+func (s *Server) handleInitialized(ctx context.Context, sess *Session, params json.RawMessage) (interface{}, *mcp.RPCError) {
+	sess.mu.Lock()
+	sess.initialized = true
+	sess.mu.Unlock()
+	logger.Debug("Client acknowledged initialization")
+
+	// The client's response arrives through the transport, so the request must
+	// not block the dispatcher. On Streamable HTTP it fails until the client
+	// opens a stream; it is retried then, or by the next request.
+	go s.ensureRoots(context.Background(), sess)
+	return nil, nil
+}
+
+// handlePing replies with an empty result.
+func (s *Server) handlePing(ctx context.Context, sess *Session, params json.RawMessage) (interface{}, *mcp.RPCError) {
+	return struct{}{}, nil
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/santoshkal/gomcp/pkg/mcp"
+	"github.com/santoshkal/gomcp/pkg/plugins"
+)
+
+// defaultClientLogLevel is the minimum level sent to clients that never called logging/setLevel.
+const defaultClientLogLevel = mcp.LevelInfo
+
+// handleLoggingSetLevel sets the minimum level of messages sent to the session.
+func (s *Server) handleLoggingSetLevel(ctx context.Context, sess *Session, params json.RawMessage) (interface{}, *mcp.RPCError) {
+	var p mcp.SetLevelParams
+	if len(params) == 0 {
+		return nil, mcp.NewError(mcp.InvalidParams, "logging/setLevel requires params")
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, mcp.NewError(mcp.InvalidParams, fmt.Sprintf("invalid logging/setLevel params: %v", err))
+	}
+	if !p.Level.Valid() {
+		return nil, mcp.NewError(mcp.InvalidParams, fmt.Sprintf("unknown logging level: %q", p.Level))
+	}
+
+	sess.mu.Lock()
+	sess.logLevel = p.Level
+	sess.mu.Unlock()
+	logger.Debugf("Session %s set log level to %s", sess.ID(), p.Level)
+	return struct{}{}, nil
+}
+
+// sessionLogger logs a handler's messages to logrus and forwards them to the
+// client as notifications/message when the session's level allows it.
+type sessionLogger struct {
+	ctx   context.Context
+	sess  *Session // nil when the call is not tied to an MCP session
+	name  string
+	entry *logrus.Entry
+}
+
+// Ensure sessionLogger implements plugins.Logger.
+var _ plugins.Logger = (*sessionLogger)(nil)
+
+// withLogger attaches a logger named after the tool or resource being served.
+func withLogger(ctx context.Context, sess *Session, name string) context.Context {
+	fields := logrus.Fields{"logger": name}
+	if sess != nil {
+		fields["session"] = sess.ID()
+	}
+	return plugins.WithLogger(ctx, &sessionLogger{ctx: ctx, sess: sess, name: name, entry: logger.WithFields(fields)})
+}
+
+func (l *sessionLogger) Debugf(format string, args ...interface{}) {
+	l.log(logrus.DebugLevel, mcp.LevelDebug, format, args...)
+}
+
+func (l *sessionLogger) Infof(format string, args ...interface{}) {
+	l.log(logrus.InfoLevel, mcp.LevelInfo, format, args...)
+}
+
+func (l *sessionLogger) Warnf(format string, args ...interface{}) {
+	l.log(logrus.WarnLevel, mcp.LevelWarning, format, args...)
+}
+
+func (l *sessionLogger) Errorf(format string, args ...interface{}) {
+	l.log(logrus.ErrorLevel, mcp.LevelError, format, args...)
+}
+
+// log writes the message to logrus and, if wanted, to the client.
+func (l *sessionLogger) log(level logrus.Level, clientLevel mcp.LoggingLevel, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	l.entry.Log(level, msg)
+
+	if l.sess == nil || !clientLevel.AtLeast(l.sess.LogLevel()) {
+		return
+	}
+	if err := notify(l.ctx, l.sess, mcp.NotificationLoggingMessage, mcp.LoggingMessageParams{
+		Level:  clientLevel,
+		Logger: l.name,
+		Data:   msg,
+	}); err != nil {
+		logger.Debugf("failed to forward log message to session %s: %v", l.sess.ID(), err)
+	}
+}
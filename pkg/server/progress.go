@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/santoshkal/gomcp/pkg/mcp"
+	"github.com/santoshkal/gomcp/pkg/plugins"
+)
+
+// progressReporter forwards handler progress to the client as notifications/progress.
+type progressReporter struct {
+	ctx   context.Context
+	sess  *Session
+	token json.RawMessage
+
+	mu   sync.Mutex
+	last float64
+	sent bool
+}
+
+// Ensure progressReporter implements plugins.ProgressReporter.
+var _ plugins.ProgressReporter = (*progressReporter)(nil)
+
+// withProgress attaches a progress reporter to ctx when the request carries a progress token.
+func withProgress(ctx context.Context, sess *Session, meta *mcp.RequestMeta) context.Context {
+	if meta == nil || len(meta.ProgressToken) == 0 {
+		return ctx
+	}
+	return plugins.WithProgressReporter(ctx, &progressReporter{ctx: ctx, sess: sess, token: meta.ProgressToken})
+}
+
+// Report implements plugins.ProgressReporter.
+func (p *progressReporter) Report(progress, total float64, message string) error {
+	p.mu.Lock()
+	if p.sent && progress <= p.last {
+		p.mu.Unlock()
+		return fmt.Errorf("progress must increase: got %v after %v", progress, p.last)
+	}
+	p.last, p.sent = progress, true
+	p.mu.Unlock()
+
+	if err := p.ctx.Err(); err != nil {
+		return err
+	}
+	return notify(p.ctx, p.sess, mcp.NotificationProgress, mcp.ProgressParams{
+		ProgressToken: p.token,
+		Progress:      progress,
+		Total:         total,
+		Message:       message,
+	})
+}
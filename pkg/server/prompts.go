@@ -0,0 +1,150 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/santoshkal/gomcp/pkg/mcp"
+)
+
+// RegisteredPrompt holds metadata and the message templates of a prompt.
+type RegisteredPrompt struct {
+	Name        string
+	Description string
+	Arguments   []mcp.PromptArgument
+	Messages    []mcp.PromptMessageTemplate
+	ServiceName string
+}
+
+// Ensure Server implements mcp.PromptRegistry.
+var _ mcp.PromptRegistry = (*Server)(nil)
+
+// RegisterPrompt implements the mcp.PromptRegistry interface.
+func (s *Server) RegisterPrompt(name, description string, arguments []mcp.PromptArgument, messages []mcp.PromptMessageTemplate) {
+	logger.Debugf("Registering prompt: %s", name)
+	s.promptsMu.Lock()
+	s.prompts[name] = RegisteredPrompt{
+		Name:        name,
+		Description: description,
+		Arguments:   arguments,
+		Messages:    messages,
+	}
+	s.promptsMu.Unlock()
+	s.listChanged(mcp.NotificationPromptsListChanged)
+}
+
+// UnregisterPrompt implements the mcp.PromptRegistry interface.
+func (s *Server) UnregisterPrompt(name string) bool {
+	s.promptsMu.Lock()
+	_, ok := s.prompts[name]
+	delete(s.prompts, name)
+	s.promptsMu.Unlock()
+	if ok {
+		logger.Debugf("Unregistered prompt: %s", name)
+		s.listChanged(mcp.NotificationPromptsListChanged)
+	}
+	return ok
+}
+
+// prompt returns the registered prompt with the given name.
+func (s *Server) prompt(name string) (RegisteredPrompt, bool) {
+	s.promptsMu.RLock()
+	defer s.promptsMu.RUnlock()
+	p, ok := s.prompts[name]
+	return p, ok
+}
+
+// handlePromptsList returns a page of registered prompts sorted by name.
+func (s *Server) handlePromptsList(ctx context.Context, sess *Session, params json.RawMessage) (interface{}, *mcp.RPCError) {
+	var p mcp.PaginatedParams
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, mcp.NewError(mcp.InvalidParams, fmt.Sprintf("invalid prompts/list params: %v", err))
+		}
+	}
+
+	s.promptsMu.RLock()
+	names := make([]string, 0, len(s.prompts))
+	prompts := make(map[string]RegisteredPrompt, len(s.prompts))
+	for name, prompt := range s.prompts {
+		names = append(names, name)
+		prompts[name] = prompt
+	}
+	s.promptsMu.RUnlock()
+	sort.Strings(names)
+
+	page, next, err := paginate(names, p.Cursor)
+	if err != nil {
+		return nil, mcp.NewError(mcp.InvalidParams, err.Error())
+	}
+
+	result := mcp.ListPromptsResult{Prompts: make([]mcp.Prompt, 0, len(page)), NextCursor: next}
+	for _, name := range page {
+		prompt := prompts[name]
+		result.Prompts = append(result.Prompts, mcp.Prompt{
+			Name:        prompt.Name,
+			Description: prompt.Description,
+			Arguments:   prompt.Arguments,
+		})
+	}
+	return result, nil
+}
+
+// handlePromptsGet validates the arguments and renders a prompt's messages.
+func (s *Server) handlePromptsGet(ctx context.Context, sess *Session, params json.RawMessage) (interface{}, *mcp.RPCError) {
+	var p mcp.GetPromptParams
+	if len(params) == 0 {
+		return nil, mcp.NewError(mcp.InvalidParams, "prompts/get requires params")
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, mcp.NewError(mcp.InvalidParams, fmt.Sprintf("invalid prompts/get params: %v", err))
+	}
+
+	prompt, exists := s.prompt(p.Name)
+	if !exists {
+		return nil, mcp.NewError(mcp.InvalidParams, fmt.Sprintf("unknown prompt: %s", p.Name))
+	}
+	if err := validatePromptArguments(prompt, p.Arguments); err != nil {
+		return nil, mcp.NewError(mcp.InvalidParams, err.Error())
+	}
+
+	// Every declared argument is defined in the template data, even when omitted.
+	data := make(map[string]string, len(prompt.Arguments))
+	for _, arg := range prompt.Arguments {
+		data[arg.Name] = p.Arguments[arg.Name]
+	}
+
+	result := mcp.GetPromptResult{Description: prompt.Description}
+	for _, msg := range prompt.Messages {
+		var buf bytes.Buffer
+		if err := msg.Template.Execute(&buf, data); err != nil {
+			logger.Errorf("[prompts/get] Prompt %s failed to render: %v", p.Name, err)
+			return nil, mcp.NewError(mcp.InternalError, fmt.Sprintf("failed to render prompt %s: %v", p.Name, err))
+		}
+		result.Messages = append(result.Messages, mcp.PromptMessage{
+			Role:    msg.Role,
+			Content: mcp.NewTextContent(buf.String()),
+		})
+	}
+	return result, nil
+}
+
+// validatePromptArguments rejects missing required arguments and unknown arguments.
+func validatePromptArguments(prompt RegisteredPrompt, args map[string]string) error {
+	declared := make(map[string]bool, len(prompt.Arguments))
+	for _, arg := range prompt.Arguments {
+		declared[arg.Name] = true
+		if arg.Required && args[arg.Name] == "" {
+			return fmt.Errorf("prompt %s requires argument %q", prompt.Name, arg.Name)
+		}
+	}
+	for name := range args {
+		if !declared[name] {
+			return fmt.Errorf("prompt %s has no argument %q", prompt.Name, name)
+		}
+	}
+	return nil
+}
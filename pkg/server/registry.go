@@ -0,0 +1,224 @@
+package server
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/santoshkal/gomcp/pkg/mcp"
+)
+
+// toolRegistry holds the registered tools and services. It is safe for
+// concurrent use; readers work on snapshots so handlers never hold its lock.
+// Tools of disabled services stay registered but are hidden from lookups.
+type toolRegistry struct {
+	mu       sync.RWMutex
+	tools    map[string]RegisteredTool
+	services map[string]*serviceEntry
+}
+
+// serviceEntry is a registered service: a Go Service or one declared in configuration.
+type serviceEntry struct {
+	info    mcp.ServiceInfo
+	service Service // nil for services declared with DefineService
+}
+
+// newToolRegistry returns an empty registry.
+func newToolRegistry() *toolRegistry {
+	return &toolRegistry{
+		tools:    make(map[string]RegisteredTool),
+		services: make(map[string]*serviceEntry),
+	}
+}
+
+// visible reports whether tool may be listed and called. r.mu must be held.
+func (r *toolRegistry) visible(tool RegisteredTool) bool {
+	if tool.ServiceName == "" {
+		return true
+	}
+	entry, ok := r.services[tool.ServiceName]
+	return !ok || entry.info.Enabled
+}
+
+// addTool stores tool, replacing any tool with the same name. It reports
+// whether a tool was replaced.
+func (r *toolRegistry) addTool(tool RegisteredTool) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, replaced := r.tools[tool.Name]
+	r.tools[tool.Name] = withSlots(tool)
+	return replaced
+}
+
+// removeTool deletes the named tool and reports whether it existed.
+func (r *toolRegistry) removeTool(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.tools[name]
+	delete(r.tools, name)
+	return ok
+}
+
+// tool returns the named tool unless its service is disabled.
+func (r *toolRegistry) tool(name string) (RegisteredTool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tool, ok := r.tools[name]
+	if !ok || !r.visible(tool) {
+		return RegisteredTool{}, false
+	}
+	return tool, true
+}
+
+// len returns the number of available tools.
+func (r *toolRegistry) len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	n := 0
+	for _, tool := range r.tools {
+		if r.visible(tool) {
+			n++
+		}
+	}
+	return n
+}
+
+// snapshot returns the available tools sorted by name.
+func (r *toolRegistry) snapshot() []RegisteredTool {
+	r.mu.RLock()
+	tools := make([]RegisteredTool, 0, len(r.tools))
+	for _, tool := range r.tools {
+		if r.visible(tool) {
+			tools = append(tools, tool)
+		}
+	}
+	r.mu.RUnlock()
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools
+}
+
+// addService stores a Go service under its name, keeping the metadata and
+// state of a service already defined with that name.
+func (r *toolRegistry) addService(service Service) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if entry, ok := r.services[service.Name()]; ok {
+		entry.service = service
+		return
+	}
+	r.services[service.Name()] = &serviceEntry{info: mcp.ServiceInfo{Name: service.Name(), Enabled: true}, service: service}
+}
+
+// defineService stores or updates the metadata of a service. It reports
+// whether the service's enabled state changed.
+func (r *toolRegistry) defineService(info mcp.ServiceInfo) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if entry, ok := r.services[info.Name]; ok {
+		changed := entry.info.Enabled != info.Enabled
+		entry.info = info
+		return changed
+	}
+	r.services[info.Name] = &serviceEntry{info: info}
+	return false
+}
+
+// service returns the metadata of the named service.
+func (r *toolRegistry) service(name string) (mcp.ServiceInfo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry, ok := r.services[name]
+	if !ok {
+		return mcp.ServiceInfo{}, false
+	}
+	return entry.info, true
+}
+
+// setServiceEnabled enables or disables a service. It reports whether the
+// service exists and whether its state changed.
+func (r *toolRegistry) setServiceEnabled(name string, enabled bool) (ok, changed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry, ok := r.services[name]
+	if !ok {
+		return false, false
+	}
+	changed = entry.info.Enabled != enabled
+	entry.info.Enabled = enabled
+	return true, changed
+}
+
+// removeService deletes the named service along with its tools. It returns the
+// names of the removed tools and whether the service existed.
+func (r *toolRegistry) removeService(name string) ([]string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.services[name]
+	delete(r.services, name)
+
+	var removed []string
+	for toolName, tool := range r.tools {
+		if tool.ServiceName == name {
+			delete(r.tools, toolName)
+			removed = append(removed, toolName)
+		}
+	}
+	sort.Strings(removed)
+	return removed, ok || len(removed) > 0
+}
+
+// serviceInfos returns the metadata of the registered services sorted by name.
+func (r *toolRegistry) serviceInfos() []mcp.ServiceInfo {
+	r.mu.RLock()
+	infos := make([]mcp.ServiceInfo, 0, len(r.services))
+	for _, entry := range r.services {
+		infos = append(infos, entry.info)
+	}
+	r.mu.RUnlock()
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// apply performs the service and tool changes of update: removals first, then
+// services and tools are defined or replaced. r.mu must be held.
+func (r *toolRegistry) apply(update mcp.RegistryUpdate) {
+	for _, name := range update.RemoveServices {
+		delete(r.services, name)
+		for toolName, tool := range r.tools {
+			if tool.ServiceName == name {
+				delete(r.tools, toolName)
+			}
+		}
+	}
+	for _, name := range update.RemoveTools {
+		delete(r.tools, name)
+	}
+	for _, info := range update.Services {
+		if entry, ok := r.services[info.Name]; ok {
+			entry.info = info
+		} else {
+			r.services[info.Name] = &serviceEntry{info: info}
+		}
+	}
+	for _, tool := range update.Tools {
+		if _, ok := r.services[tool.Service]; tool.Service != "" && !ok {
+			r.services[tool.Service] = &serviceEntry{info: mcp.ServiceInfo{Name: tool.Service, Enabled: true}}
+		}
+		r.tools[tool.Name] = withSlots(RegisteredTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.InputSchema,
+			Handler:     tool.Handler,
+			ServiceName: tool.Service,
+			Limits:      tool.Limits,
+		})
+	}
+}
+
+// withSlots gives tool the semaphore that bounds its concurrent calls when it
+// sets MaxConcurrency.
+func withSlots(tool RegisteredTool) RegisteredTool {
+	if tool.Limits.MaxConcurrency > 0 {
+		tool.slots = make(chan struct{}, tool.Limits.MaxConcurrency)
+	}
+	return tool
+}
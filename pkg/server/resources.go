@@ -0,0 +1,449 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/santoshkal/gomcp/pkg/mcp"
+	"github.com/santoshkal/gomcp/pkg/plugins"
+)
+
+// resourcePollInterval is how often subscribed file resources are checked for changes.
+const resourcePollInterval = 2 * time.Second
+
+// RegisteredResource holds metadata and the reader for a resource or URI template.
+type RegisteredResource struct {
+	URI         string // concrete URI, or URI template when Template is set
+	Name        string
+	Description string
+	MimeType    string
+	Handler     plugins.ResourceHandler
+	Path        string // local file backing the resource, if any
+	ServiceName string
+	Template    bool
+
+	pattern *uriTemplate
+}
+
+// Ensure Server implements mcp.ResourceRegistry.
+var _ mcp.ResourceRegistry = (*Server)(nil)
+
+// RegisterResource implements the mcp.ResourceRegistry interface.
+func (s *Server) RegisterResource(uri, name, description, mimeType string, handler plugins.ResourceHandler) {
+	s.addResource(mcp.ResourceRegistration{URI: uri, Name: name, Description: description, MimeType: mimeType, Handler: handler})
+}
+
+// RegisterFileResource implements the mcp.ResourceRegistry interface.
+func (s *Server) RegisterFileResource(uri, name, description, mimeType, path string) {
+	s.addResource(mcp.ResourceRegistration{URI: uri, Name: name, Description: description, MimeType: mimeType, Path: path})
+}
+
+// addResource stores a resource and notifies clients of the change.
+func (s *Server) addResource(r mcp.ResourceRegistration) {
+	res, err := newResource(r)
+	if err != nil {
+		logger.Errorf("failed to register resource %s: %v", r.Name, err)
+		return
+	}
+	s.resourcesMu.Lock()
+	s.resources[res.URI] = res
+	s.resourcesMu.Unlock()
+	s.listChanged(mcp.NotificationResourcesListChanged)
+}
+
+// newResource prepares a registration for serving: it compiles the URI
+// template, if any, and guesses the MIME type of a file resource.
+func newResource(r mcp.ResourceRegistration) (RegisteredResource, error) {
+	logger.Debugf("Registering resource: %s (%s)", r.Name, r.URI)
+	res := RegisteredResource{URI: r.URI, Name: r.Name, Description: r.Description, MimeType: r.MimeType, Handler: r.Handler, Path: r.Path}
+	if res.Path != "" && res.MimeType == "" {
+		res.MimeType = mime.TypeByExtension(filepath.Ext(res.Path))
+	}
+	if strings.Contains(res.URI, "{") {
+		pattern, err := compileURITemplate(res.URI)
+		if err != nil {
+			return RegisteredResource{}, err
+		}
+		res.Template, res.pattern = true, pattern
+	}
+	return res, nil
+}
+
+// UnregisterResource implements the mcp.ResourceRegistry interface.
+func (s *Server) UnregisterResource(uri string) bool {
+	s.resourcesMu.Lock()
+	_, ok := s.resources[uri]
+	delete(s.resources, uri)
+	s.resourcesMu.Unlock()
+	if ok {
+		logger.Debugf("Unregistered resource: %s", uri)
+		s.listChanged(mcp.NotificationResourcesListChanged)
+	}
+	return ok
+}
+
+// findResource returns the resource serving uri and the template variables it matched.
+// Concrete resources take precedence over templates.
+func (s *Server) findResource(uri string) (RegisteredResource, map[string]string, bool) {
+	s.resourcesMu.RLock()
+	defer s.resourcesMu.RUnlock()
+
+	if res, ok := s.resources[uri]; ok && !res.Template {
+		return res, nil, true
+	}
+	keys := make([]string, 0, len(s.resources))
+	for key, res := range s.resources {
+		if res.Template {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		res := s.resources[key]
+		if vars, ok := res.pattern.match(uri); ok {
+			return res, vars, true
+		}
+	}
+	return RegisteredResource{}, nil, false
+}
+
+// sortedResources returns the concrete resources or the templates, sorted by URI.
+func (s *Server) sortedResources(templates bool) ([]string, map[string]RegisteredResource) {
+	s.resourcesMu.RLock()
+	defer s.resourcesMu.RUnlock()
+
+	byURI := make(map[string]RegisteredResource)
+	keys := make([]string, 0, len(s.resources))
+	for key, res := range s.resources {
+		if res.Template == templates {
+			keys = append(keys, key)
+			byURI[key] = res
+		}
+	}
+	sort.Strings(keys)
+	return keys, byURI
+}
+
+// handleResourcesList returns a page of concrete resources.
+func (s *Server) handleResourcesList(ctx context.Context, sess *Session, params json.RawMessage) (interface{}, *mcp.RPCError) {
+	var p mcp.PaginatedParams
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, mcp.NewError(mcp.InvalidParams, fmt.Sprintf("invalid resources/list params: %v", err))
+		}
+	}
+
+	keys, byURI := s.sortedResources(false)
+	page, next, err := paginate(keys, p.Cursor)
+	if err != nil {
+		return nil, mcp.NewError(mcp.InvalidParams, err.Error())
+	}
+
+	result := mcp.ListResourcesResult{Resources: make([]mcp.Resource, 0, len(page)), NextCursor: next}
+	for _, key := range page {
+		res := byURI[key]
+		result.Resources = append(result.Resources, mcp.Resource{
+			URI:         res.URI,
+			Name:        res.Name,
+			Description: res.Description,
+			MimeType:    res.MimeType,
+		})
+	}
+	return result, nil
+}
+
+// handleResourceTemplatesList returns a page of resource templates.
+func (s *Server) handleResourceTemplatesList(ctx context.Context, sess *Session, params json.RawMessage) (interface{}, *mcp.RPCError) {
+	var p mcp.PaginatedParams
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, mcp.NewError(mcp.InvalidParams, fmt.Sprintf("invalid resources/templates/list params: %v", err))
+		}
+	}
+
+	keys, byURI := s.sortedResources(true)
+	page, next, err := paginate(keys, p.Cursor)
+	if err != nil {
+		return nil, mcp.NewError(mcp.InvalidParams, err.Error())
+	}
+
+	result := mcp.ListResourceTemplatesResult{ResourceTemplates: make([]mcp.ResourceTemplate, 0, len(page)), NextCursor: next}
+	for _, key := range page {
+		res := byURI[key]
+		result.ResourceTemplates = append(result.ResourceTemplates, mcp.ResourceTemplate{
+			URITemplate: res.URI,
+			Name:        res.Name,
+			Description: res.Description,
+			MimeType:    res.MimeType,
+		})
+	}
+	return result, nil
+}
+
+// handleResourcesRead reads the contents of a resource.
+func (s *Server) handleResourcesRead(ctx context.Context, sess *Session, params json.RawMessage) (interface{}, *mcp.RPCError) {
+	p, rpcErr := decodeResourceParams(mcp.MethodResourcesRead, params)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	res, vars, exists := s.findResource(p.URI)
+	if !exists {
+		return nil, mcp.NewError(mcp.ResourceNotFound, fmt.Sprintf("resource not found: %s", p.URI))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts().Resource)
+	defer cancel()
+	ctx = withLogger(ctx, sess, res.Name)
+
+	var result interface{}
+	var err error
+	if res.Path != "" {
+		result, err = os.ReadFile(res.Path)
+	} else {
+		result, err = res.Handler(ctx, p.URI, vars)
+	}
+	if err != nil {
+		logger.Errorf("[resources/read] Resource %s failed: %v", p.URI, err)
+		return nil, mcp.NewError(mcp.InternalError, fmt.Sprintf("failed to read resource %s: %v", p.URI, err))
+	}
+
+	contents, err := resourceContents(p.URI, res.MimeType, result)
+	if err != nil {
+		return nil, mcp.NewError(mcp.InternalError, fmt.Sprintf("failed to marshal resource %s: %v", p.URI, err))
+	}
+	return mcp.ReadResourceResult{Contents: contents}, nil
+}
+
+// handleResourcesSubscribe subscribes the session to updates of a resource.
+func (s *Server) handleResourcesSubscribe(ctx context.Context, sess *Session, params json.RawMessage) (interface{}, *mcp.RPCError) {
+	p, rpcErr := decodeResourceParams(mcp.MethodResourcesSubscribe, params)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	// Clients of the stateless /rpc endpoint cannot be sent notifications.
+	if sess.stateless {
+		return nil, mcp.NewError(mcp.InvalidRequest, "resources/subscribe requires a session: use the /mcp or stdio transport")
+	}
+	res, _, exists := s.findResource(p.URI)
+	if !exists {
+		return nil, mcp.NewError(mcp.ResourceNotFound, fmt.Sprintf("resource not found: %s", p.URI))
+	}
+
+	if res.Path != "" {
+		s.watchFileResource(sess, p.URI, res.Path)
+	} else {
+		sess.subscribe(p.URI)
+	}
+	logger.Debugf("Session %s subscribed to %s", sess.ID(), p.URI)
+	return struct{}{}, nil
+}
+
+// handleResourcesUnsubscribe cancels a subscription made with resources/subscribe.
+func (s *Server) handleResourcesUnsubscribe(ctx context.Context, sess *Session, params json.RawMessage) (interface{}, *mcp.RPCError) {
+	p, rpcErr := decodeResourceParams(mcp.MethodResourcesUnsubscribe, params)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	sess.unsubscribe(p.URI)
+	logger.Debugf("Session %s unsubscribed from %s", sess.ID(), p.URI)
+	return struct{}{}, nil
+}
+
+// NotifyResourceUpdated tells every session subscribed to uri that it changed.
+func (s *Server) NotifyResourceUpdated(uri string) {
+	for _, sess := range s.sessionList() {
+		if !sess.subscribed(uri) {
+			continue
+		}
+		if err := sess.Notify(mcp.NotificationResourceUpdated, mcp.ResourceParams{URI: uri}); err != nil {
+			logger.Debugf("failed to notify session %s of update to %s: %v", sess.ID(), uri, err)
+		}
+	}
+}
+
+// hasSubscribers reports whether any session is subscribed to uri.
+func (s *Server) hasSubscribers(uri string) bool {
+	for _, sess := range s.sessionList() {
+		if sess.subscribed(uri) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchFileResource subscribes sess to a file-backed resource and polls the
+// file while sessions are subscribed to it, notifying them when it changes.
+// Subscribing and the watcher's check for subscribers hold resourcesMu, so a
+// subscription is never left without a watcher.
+func (s *Server) watchFileResource(sess *Session, uri, path string) {
+	s.resourcesMu.Lock()
+	defer s.resourcesMu.Unlock()
+	sess.subscribe(uri)
+	if s.watching[uri] {
+		return
+	}
+	s.watching[uri] = true
+
+	go func() {
+		last := fileStamp(path)
+		ticker := time.NewTicker(resourcePollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if s.stopWatching(uri) {
+				logger.Debugf("Stopped watching %s: no subscribers", path)
+				return
+			}
+			if current := fileStamp(path); current != last {
+				last = current
+				logger.Debugf("Resource %s changed", uri)
+				s.NotifyResourceUpdated(uri)
+			}
+		}
+	}()
+}
+
+// stopWatching ends the watch of uri and reports true when no session is
+// subscribed to it any more.
+func (s *Server) stopWatching(uri string) bool {
+	s.resourcesMu.Lock()
+	defer s.resourcesMu.Unlock()
+	if s.hasSubscribers(uri) {
+		return false
+	}
+	delete(s.watching, uri)
+	return true
+}
+
+// fileStamp summarizes the modification state of a file; it is empty when the file is missing.
+func fileStamp(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+}
+
+// decodeResourceParams decodes the uri parameter shared by the resource methods.
+func decodeResourceParams(method string, params json.RawMessage) (mcp.ResourceParams, *mcp.RPCError) {
+	var p mcp.ResourceParams
+	if len(params) == 0 {
+		return p, mcp.NewError(mcp.InvalidParams, fmt.Sprintf("%s requires params", method))
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return p, mcp.NewError(mcp.InvalidParams, fmt.Sprintf("invalid %s params: %v", method, err))
+	}
+	if p.URI == "" {
+		return p, mcp.NewError(mcp.InvalidParams, fmt.Sprintf("%s requires a uri", method))
+	}
+	return p, nil
+}
+
+// resourceContents renders a reader result. Text is returned as-is, binary data is
+// base64 encoded, and any other value is encoded as JSON.
+func resourceContents(uri, mimeType string, result interface{}) ([]mcp.ResourceContents, error) {
+	switch v := result.(type) {
+	case []mcp.ResourceContents:
+		return v, nil
+	case string:
+		return []mcp.ResourceContents{{URI: uri, MimeType: mimeType, Text: v}}, nil
+	case []byte:
+		if isTextMimeType(mimeType) || (mimeType == "" && utf8.Valid(v)) {
+			return []mcp.ResourceContents{{URI: uri, MimeType: mimeType, Text: string(v)}}, nil
+		}
+		return []mcp.ResourceContents{{URI: uri, MimeType: mimeType, Blob: base64.StdEncoding.EncodeToString(v)}}, nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	if mimeType == "" {
+		mimeType = "application/json"
+	}
+	return []mcp.ResourceContents{{URI: uri, MimeType: mimeType, Text: string(data)}}, nil
+}
+
+// isTextMimeType reports whether content of the given MIME type is textual.
+func isTextMimeType(mimeType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(mimeType)
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "json") ||
+		strings.HasSuffix(mediaType, "yaml") ||
+		strings.HasSuffix(mediaType, "xml")
+}
+
+// uriTemplate matches URIs against an RFC 6570 template using simple {var} and
+// reserved {+var} expansions.
+type uriTemplate struct {
+	re   *regexp.Regexp
+	vars []string
+}
+
+// compileURITemplate converts a URI template into a matcher.
+func compileURITemplate(tmpl string) (*uriTemplate, error) {
+	var pattern bytes.Buffer
+	var vars []string
+	pattern.WriteString("^")
+	rest := tmpl
+	for rest != "" {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			pattern.WriteString(regexp.QuoteMeta(rest))
+			break
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated expression in URI template %q", tmpl)
+		}
+		pattern.WriteString(regexp.QuoteMeta(rest[:open]))
+
+		name := rest[open+1 : open+end]
+		if strings.HasPrefix(name, "+") {
+			name = name[1:]
+			pattern.WriteString("(.+)")
+		} else {
+			pattern.WriteString("([^/?#]+)")
+		}
+		if name == "" || strings.ContainsAny(name, "{,*:") {
+			return nil, fmt.Errorf("unsupported expression {%s} in URI template %q", name, tmpl)
+		}
+		vars = append(vars, name)
+		rest = rest[open+end+1:]
+	}
+	pattern.WriteString("$")
+
+	re, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid URI template %q: %w", tmpl, err)
+	}
+	return &uriTemplate{re: re, vars: vars}, nil
+}
+
+// match returns the variable values of uri when it matches the template.
+func (t *uriTemplate) match(uri string) (map[string]string, bool) {
+	groups := t.re.FindStringSubmatch(uri)
+	if groups == nil {
+		return nil, false
+	}
+	vars := make(map[string]string, len(t.vars))
+	for i, name := range t.vars {
+		value, err := url.PathUnescape(groups[i+1])
+		if err != nil {
+			value = groups[i+1]
+		}
+		vars[name] = value
+	}
+	return vars, true
+}
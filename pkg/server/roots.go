@@ -0,0 +1,217 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/santoshkal/gomcp/pkg/mcp"
+	"github.com/santoshkal/gomcp/pkg/plugins"
+)
+
+// rootsTimeout bounds a roots/list request to the client.
+const rootsTimeout = 30 * time.Second
+
+// pathFormat marks schema properties whose values are filesystem paths that
+// must lie within the client's roots, e.g. {"type": "string", "format": "path"}.
+const pathFormat = "path"
+
+// handleRootsListChanged refreshes the session's roots after the client changed them.
+func (s *Server) handleRootsListChanged(ctx context.Context, sess *Session, params json.RawMessage) (interface{}, *mcp.RPCError) {
+	if sess.ClientCapabilities().Roots == nil {
+		logger.Debugf("Ignoring roots/list_changed from session %s without roots capability", sess.ID())
+		return nil, nil
+	}
+	go s.listRoots(context.Background(), sess)
+	return nil, nil
+}
+
+// listRoots requests the client's roots over the stream selected by ctx and
+// stores them in the session. When the request fails the previous roots are kept.
+func (s *Server) listRoots(ctx context.Context, sess *Session) {
+	ctx, cancel := context.WithTimeout(ctx, rootsTimeout)
+	defer cancel()
+
+	var result mcp.ListRootsResult
+	if err := request(ctx, sess, mcp.MethodRootsList, nil, &result); err != nil {
+		logger.Warnf("failed to list roots of session %s: %v", sess.ID(), err)
+		return
+	}
+
+	roots := make([]plugins.Root, 0, len(result.Roots))
+	for _, root := range result.Roots {
+		if _, err := rootPath(root.URI); err != nil {
+			logger.Warnf("Ignoring root of session %s: %v", sess.ID(), err)
+			continue
+		}
+		roots = append(roots, plugins.Root{URI: root.URI, Name: root.Name})
+	}
+	sess.mu.Lock()
+	sess.roots, sess.rootsKnown = roots, true
+	sess.mu.Unlock()
+	logger.Infof("Session %s declared %d roots", sess.ID(), len(roots))
+}
+
+// ensureRoots lists the client's roots unless they are already known, the
+// client did not declare the roots capability, or the handshake is not
+// complete. Concurrent callers wait for a single roots/list request.
+func (s *Server) ensureRoots(ctx context.Context, sess *Session) {
+	sess.mu.Lock()
+	if sess.clientCaps.Roots == nil || !sess.initialized || sess.rootsKnown {
+		sess.mu.Unlock()
+		return
+	}
+	if fetching := sess.rootsFetching; fetching != nil {
+		sess.mu.Unlock()
+		select {
+		case <-fetching:
+		case <-ctx.Done():
+		}
+		return
+	}
+	fetching := make(chan struct{})
+	sess.rootsFetching = fetching
+	sess.mu.Unlock()
+
+	defer func() {
+		sess.mu.Lock()
+		sess.rootsFetching = nil
+		sess.mu.Unlock()
+		close(fetching)
+	}()
+	s.listRoots(ctx, sess)
+}
+
+// Roots returns the roots declared by the client. ok is false until the client
+// has listed them.
+func (sess *Session) Roots() (roots []plugins.Root, ok bool) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.roots, sess.rootsKnown
+}
+
+// rootsUnknownKey marks a context whose client declared roots that could not
+// be listed yet.
+type rootsUnknownKey struct{}
+
+// withRoots attaches the session's roots to ctx. When the client declared roots
+// that are not known yet, for example because it had no open stream after the
+// handshake, they are requested first over the request's own stream. If they
+// are still unknown, ctx is marked so that checkRoots rejects path arguments.
+func (s *Server) withRoots(ctx context.Context, sess *Session) context.Context {
+	if sess.ClientCapabilities().Roots == nil {
+		return ctx
+	}
+	s.ensureRoots(ctx, sess)
+	roots, ok := sess.Roots()
+	if !ok {
+		return context.WithValue(ctx, rootsUnknownKey{}, true)
+	}
+	return plugins.WithRoots(ctx, roots)
+}
+
+// checkRoots rejects path arguments of a tool call that fall outside the roots
+// in ctx. Path arguments are the properties of the tool's schema declared with
+// format "path", or arrays of them. Once the client declared roots it fails
+// closed: a path argument that is not a string, or a list of them, is rejected
+// too, and so is every path while the roots are not known.
+func checkRoots(ctx context.Context, tool RegisteredTool, params map[string]interface{}) error {
+	roots, ok := plugins.RootsFromContext(ctx)
+	unknown, _ := ctx.Value(rootsUnknownKey{}).(bool)
+	if !ok && !unknown {
+		return nil
+	}
+	properties, _ := tool.InputSchema["properties"].(map[string]interface{})
+	for name, property := range properties {
+		value, present := params[name]
+		if !present || value == nil {
+			continue
+		}
+		prop, _ := property.(map[string]interface{})
+		var paths []interface{}
+		switch {
+		case prop["format"] == pathFormat:
+			paths = []interface{}{value}
+		case prop["type"] == "array":
+			items, _ := prop["items"].(map[string]interface{})
+			if items["format"] != pathFormat {
+				continue
+			}
+			if paths, ok = value.([]interface{}); !ok {
+				return fmt.Errorf("argument %s: expected a list of paths", name)
+			}
+		}
+		for _, p := range paths {
+			path, isString := p.(string)
+			if !isString {
+				return fmt.Errorf("argument %s: expected a path, got %T", name, p)
+			}
+			if unknown {
+				return fmt.Errorf("argument %s: the client's roots are not known yet", name)
+			}
+			if !withinRoots(path, roots) {
+				return fmt.Errorf("argument %s: path %s is outside the client's roots", name, path)
+			}
+		}
+	}
+	return nil
+}
+
+// withinRoots reports whether path, a local path or file:// URI, lies inside one of roots.
+func withinRoots(path string, roots []plugins.Root) bool {
+	if strings.HasPrefix(path, "file://") {
+		p, err := rootPath(path)
+		if err != nil {
+			return false
+		}
+		path = p
+	}
+	path = resolvePath(path)
+	for _, root := range roots {
+		dir, err := rootPath(root.URI)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(resolvePath(dir), path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// rootPath returns the local path named by a file:// URI.
+func rootPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("invalid root URI %q: %v", uri, err)
+	}
+	if u.Scheme != "file" || u.Path == "" {
+		return "", fmt.Errorf("root URI %q is not a file:// URI", uri)
+	}
+	return filepath.FromSlash(u.Path), nil
+}
+
+// resolvePath makes path absolute and resolves symbolic links in its longest
+// existing prefix, so that links cannot escape a root.
+func resolvePath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	dir, rest := path, ""
+	for {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return path
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+		dir = parent
+	}
+}
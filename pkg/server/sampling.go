@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/santoshkal/gomcp/pkg/mcp"
+	"github.com/santoshkal/gomcp/pkg/plugins"
+)
+
+// defaultSamplingMaxTokens is used when a sampling request does not bound its length.
+const defaultSamplingMaxTokens = 1024
+
+// clientSampler forwards sampling requests to the client as sampling/createMessage.
+type clientSampler struct {
+	sess *Session
+}
+
+// Ensure clientSampler implements plugins.Sampler.
+var _ plugins.Sampler = (*clientSampler)(nil)
+
+// withSampler lets handlers sample from the model of the session's client.
+func withSampler(ctx context.Context, sess *Session) context.Context {
+	return plugins.WithSampler(ctx, &clientSampler{sess: sess})
+}
+
+// CreateMessage implements plugins.Sampler.
+func (c *clientSampler) CreateMessage(ctx context.Context, req plugins.SamplingRequest) (plugins.SamplingResult, error) {
+	if c.sess.ClientCapabilities().Sampling == nil {
+		return plugins.SamplingResult{}, plugins.ErrSamplingUnsupported
+	}
+	if len(req.Messages) == 0 {
+		return plugins.SamplingResult{}, fmt.Errorf("sampling request has no messages")
+	}
+
+	params := mcp.CreateMessageParams{
+		SystemPrompt:  req.SystemPrompt,
+		MaxTokens:     req.MaxTokens,
+		StopSequences: req.StopSequences,
+	}
+	if params.MaxTokens <= 0 {
+		params.MaxTokens = defaultSamplingMaxTokens
+	}
+	if req.Temperature != 0 {
+		params.Temperature = &req.Temperature
+	}
+	if len(req.ModelHints) > 0 {
+		params.ModelPreferences = &mcp.ModelPreferences{}
+		for _, name := range req.ModelHints {
+			params.ModelPreferences.Hints = append(params.ModelPreferences.Hints, mcp.ModelHint{Name: name})
+		}
+	}
+	for _, msg := range req.Messages {
+		if msg.Role != "user" && msg.Role != "assistant" {
+			return plugins.SamplingResult{}, fmt.Errorf("invalid sampling message role %q: expected user or assistant", msg.Role)
+		}
+		params.Messages = append(params.Messages, mcp.SamplingMessage{Role: msg.Role, Content: mcp.NewTextContent(msg.Text)})
+	}
+
+	logger.Debugf("Requesting sampling from session %s", c.sess.ID())
+	var result mcp.CreateMessageResult
+	if err := request(ctx, c.sess, mcp.MethodSamplingCreateMessage, params, &result); err != nil {
+		return plugins.SamplingResult{}, err
+	}
+	if result.Content.Type != "text" {
+		return plugins.SamplingResult{}, fmt.Errorf("unsupported sampling content type %q", result.Content.Type)
+	}
+	return plugins.SamplingResult{
+		Role:       result.Role,
+		Text:       result.Content.Text,
+		Model:      result.Model,
+		StopReason: result.StopReason,
+	}, nil
+}
@@ -8,11 +8,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/rpc"
-	"net/rpc/jsonrpc"
 	"os"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 	"github.com/tmc/langchaingo/llms"
@@ -33,6 +31,7 @@ func init() {
 		FullTimestamp: true,
 	})
 	logger.SetLevel(logrus.DebugLevel)
+	reg.SetLogger(logger)
 }
 
 // RegisteredTool holds metadata and the handler for a tool.
@@ -42,110 +41,330 @@ type RegisteredTool struct {
 	InputSchema map[string]interface{}
 	Handler     plugins.ToolHandler
 	ServiceName string
+	Limits      mcp.ToolLimits
+
+	slots chan struct{} // bounds concurrent calls when Limits.MaxConcurrency is set
 }
 
-// Service defines an interface for a service to register its tools.
+// Service defines an interface for a service to register its tools. The
+// registry it is given groups the tools it registers under the service.
 type Service interface {
 	Name() string
-	RegisterTools(s *Server)
+	RegisterTools(r mcp.Registry)
 }
 
 // Server represents the composite server and implements mcp.Registry.
 // It is now completely independent of any technology-specific code.
 type Server struct {
-	llm      *openai.LLM
-	tools    map[string]RegisteredTool
-	services map[string]Service
+	llm      *openai.LLM // nil when LLM requests are sampled from the client
+	registry *toolRegistry
+	config   *reg.Loader
+	methods  map[string]methodHandler
+
+	settingsMu sync.RWMutex
+	settings   reg.ServerConfig
+
+	sessionsMu sync.RWMutex
+	sessions   map[string]*Session
+
+	resourcesMu sync.RWMutex
+	resources   map[string]RegisteredResource // keyed by URI or URI template
+	watching    map[string]bool               // file resources being polled
+
+	promptsMu sync.RWMutex
+	prompts   map[string]RegisteredPrompt
+
+	completionsMu sync.RWMutex
+	completions   map[string]*completionSource // keyed by mcp.CompletionRef.Key
+
+	// listChangedPending coalesces bursts of registry changes into a single
+	// list_changed notification per list.
+	listChangedMu      sync.Mutex
+	listChangedPending map[string]bool
 }
 
-// Ensure Server implements mcp.Registry.
-var _ mcp.Registry = (*Server)(nil)
+// Ensure Server implements mcp.UpdatableRegistry.
+var _ mcp.UpdatableRegistry = (*Server)(nil)
 
 // NewServer initializes a new Server instance and registers dynamic tools.
 func NewServer() (*Server, error) {
 	logger.Debug("Entering NewServer")
 	defer logger.Debug("Exiting NewServer")
 
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("OPENAI_API_KEY environment variable not set")
+	configPath := reg.ResolveConfigPath()
+	logger.Infof("Config Path: %v", configPath)
+	settings, err := reg.LoadServerConfig(configPath)
+	if err != nil {
+		logger.Errorf("failed to read server settings from %s, using defaults: %v", configPath, err)
 	}
-	llm, err := openai.New(openai.WithToken(apiKey), openai.WithModel("gpt-4o"))
+
+	llm, err := newLLM(settings.LLM)
 	if err != nil {
 		return nil, err
 	}
 
 	s := &Server{
 		llm:      llm,
-		tools:    make(map[string]RegisteredTool),
-		services: make(map[string]Service),
+		registry: newToolRegistry(),
+
+		sessions:    make(map[string]*Session),
+		resources:   make(map[string]RegisteredResource),
+		watching:    make(map[string]bool),
+		prompts:     make(map[string]RegisteredPrompt),
+		completions: make(map[string]*completionSource),
+
+		listChangedPending: make(map[string]bool),
 	}
+	s.methods = s.mcpMethods()
+	s.applySettings(settings)
 
 	// Dynamically load and register tools from YAML configuration.
-	//homeDir := os.Getenv("HOME")
-	//configPath := homeDir + "/gomcp/plug.yaml"
-	configPath := "/workspaces/gomcp/plug.yaml"
-	logger.Infof("Config Path: %v", configPath)
-	if configPath == "" {
-		configPath = "plug.yaml" // Default configuration file.
-	}
-	if err := reg.RegisterToolsFromConfig(s, configPath); err != nil {
+	s.config = reg.NewLoader(configPath)
+	if _, err := s.config.Reload(s); err != nil {
 		logger.Errorf("failed to register dynamic tools from config: %v", err)
 		// Optionally, you can return the error if dynamic tools are critical.
 	}
+	go s.watchConfig()
 
 	return s, nil
 }
 
+// newLLM creates the LLM used to plan instructions. It returns nil when LLM
+// requests are delegated to the client's model through MCP sampling.
+func newLLM(cfg reg.LLMConfig) (*openai.LLM, error) {
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	switch cfg.Provider {
+	case "sampling":
+		logger.Info("LLM requests will use client sampling")
+		return nil, nil
+	case "openai":
+		if apiKey == "" {
+			return nil, fmt.Errorf("OPENAI_API_KEY environment variable not set")
+		}
+	default:
+		if apiKey == "" {
+			logger.Info("OPENAI_API_KEY not set, LLM requests will use client sampling")
+			return nil, nil
+		}
+	}
+	return openai.New(openai.WithToken(apiKey), openai.WithModel(cfg.Model))
+}
+
 // RegisterTool implements the mcp.Registry interface.
 func (s *Server) RegisterTool(name, description string, inputSchema map[string]interface{}, handler plugins.ToolHandler) {
-	logger.Debugf("Registering tool: %s", name)
-	s.tools[name] = RegisteredTool{
+	s.registerTool(RegisteredTool{
 		Name:        name,
 		Description: description,
 		InputSchema: inputSchema,
 		Handler:     handler,
+	})
+}
+
+// RegisterServiceTool implements the mcp.Registry interface. A service that was
+// not defined yet is defined as enabled.
+func (s *Server) RegisterServiceTool(service, name, description string, inputSchema map[string]interface{}, handler plugins.ToolHandler) {
+	if _, ok := s.registry.service(service); !ok {
+		s.registry.defineService(mcp.ServiceInfo{Name: service, Enabled: true})
+	}
+	s.registerTool(RegisteredTool{
+		Name:        name,
+		Description: description,
+		InputSchema: inputSchema,
+		Handler:     handler,
+		ServiceName: service,
+	})
+}
+
+// registerTool adds or replaces a tool and notifies clients of the change.
+func (s *Server) registerTool(tool RegisteredTool) {
+	if s.registry.addTool(tool) {
+		logger.Debugf("Replacing tool: %s", tool.Name)
+	} else {
+		logger.Debugf("Registering tool: %s", tool.Name)
+	}
+	s.toolsChanged()
+}
+
+// UnregisterTool removes a tool. It reports whether the tool was registered.
+func (s *Server) UnregisterTool(name string) bool {
+	if !s.registry.removeTool(name) {
+		return false
+	}
+	logger.Debugf("Unregistered tool: %s", name)
+	s.toolsChanged()
+	return true
+}
+
+// DefineService implements the mcp.Registry interface.
+func (s *Server) DefineService(info mcp.ServiceInfo) {
+	logger.Debugf("Defining service: %s (enabled: %t)", info.Name, info.Enabled)
+	if s.registry.defineService(info) {
+		s.toolsChanged()
+	}
+}
+
+// SetServiceEnabled enables or disables a service. The tools of a disabled
+// service are neither listed nor callable. It reports whether the service exists.
+func (s *Server) SetServiceEnabled(name string, enabled bool) bool {
+	ok, changed := s.registry.setServiceEnabled(name, enabled)
+	if changed {
+		logger.Infof("Service %s enabled: %t", name, enabled)
+		s.toolsChanged()
+	}
+	return ok
+}
+
+// Services returns the metadata of the registered services sorted by name.
+func (s *Server) Services() []mcp.ServiceInfo {
+	return s.registry.serviceInfos()
+}
+
+// ApplyUpdate implements the mcp.UpdatableRegistry interface. Every registry
+// is locked while the update is applied, so requests see either the old or the
+// new configuration. Resources are prepared beforehand, so nothing that can
+// fail runs under the locks.
+func (s *Server) ApplyUpdate(update mcp.RegistryUpdate) {
+	logger.Debugf("Applying registry update: %d services, %d tools, %d resources and %d prompts removed, %d services, %d tools, %d resources and %d prompts registered",
+		len(update.RemoveServices), len(update.RemoveTools), len(update.RemoveResources), len(update.RemovePrompts),
+		len(update.Services), len(update.Tools), len(update.Resources), len(update.Prompts))
+	resources := make([]RegisteredResource, 0, len(update.Resources))
+	for _, r := range update.Resources {
+		res, err := newResource(r)
+		if err != nil {
+			logger.Errorf("failed to register resource %s: %v", r.Name, err)
+			continue
+		}
+		resources = append(resources, res)
+	}
+
+	s.registry.mu.Lock()
+	s.resourcesMu.Lock()
+	s.promptsMu.Lock()
+	s.completionsMu.Lock()
+	s.registry.apply(update)
+	for _, uri := range update.RemoveResources {
+		delete(s.resources, uri)
+	}
+	for _, name := range update.RemovePrompts {
+		delete(s.prompts, name)
+	}
+	for _, ref := range update.RemoveCompletions {
+		delete(s.completions, ref.Key())
+	}
+	for _, res := range resources {
+		s.resources[res.URI] = res
+	}
+	for _, p := range update.Prompts {
+		logger.Debugf("Registering prompt: %s", p.Name)
+		s.prompts[p.Name] = RegisteredPrompt{Name: p.Name, Description: p.Description, Arguments: p.Arguments, Messages: p.Messages}
+	}
+	for _, c := range update.Completions {
+		s.addCompletion(c)
+	}
+	s.completionsMu.Unlock()
+	s.promptsMu.Unlock()
+	s.resourcesMu.Unlock()
+	s.registry.mu.Unlock()
+
+	if len(update.RemoveServices)+len(update.RemoveTools)+len(update.Services)+len(update.Tools) > 0 {
+		s.toolsChanged()
+	}
+	if len(update.RemoveResources)+len(update.Resources) > 0 {
+		s.listChanged(mcp.NotificationResourcesListChanged)
+	}
+	if len(update.RemovePrompts)+len(update.Prompts) > 0 {
+		s.listChanged(mcp.NotificationPromptsListChanged)
 	}
 }
 
-// RegisterService registers a service and lets it add its tools.
+// RegisterService registers a service and lets it add its tools. The tools it
+// registers belong to the service, so UnregisterService removes them.
 func (s *Server) RegisterService(service Service) {
 	logger.Debugf("Registering service: %s", service.Name())
-	s.services[service.Name()] = service
-	service.RegisterTools(s)
+	s.registry.addService(service)
+	service.RegisterTools(&serviceRegistrar{Server: s, service: service.Name()})
+}
+
+// serviceRegistrar is the registry a Go service registers its tools with. It
+// assigns the tools registered with RegisterTool to the service.
+type serviceRegistrar struct {
+	*Server
+	service string
+}
+
+// RegisterTool implements the mcp.Registry interface.
+func (r *serviceRegistrar) RegisterTool(name, description string, inputSchema map[string]interface{}, handler plugins.ToolHandler) {
+	r.RegisterServiceTool(r.service, name, description, inputSchema, handler)
+}
+
+// UnregisterService removes a service and the tools that belong to it. It
+// reports whether anything was removed.
+func (s *Server) UnregisterService(name string) bool {
+	removed, ok := s.registry.removeService(name)
+	if !ok {
+		return false
+	}
+	logger.Debugf("Unregistered service %s with tools %v", name, removed)
+	if len(removed) > 0 {
+		s.toolsChanged()
+	}
+	return true
 }
 
-// listTools returns a slice of strings listing all registered tools.
+// listTools returns a slice of strings listing all registered tools, sorted by name.
 func (s *Server) listTools() []string {
 	var toolList []string
-	for _, tool := range s.tools {
+	for _, tool := range s.registry.snapshot() {
 		toolList = append(toolList, fmt.Sprintf("%s: %s", tool.Name, tool.Description))
 	}
 	return toolList
 }
 
-// listServices returns a slice of strings listing all registered services.
+// listServices returns a slice of strings listing the enabled services, sorted by name.
 func (s *Server) listServices() []string {
 	var serviceList []string
-	for _, svc := range s.services {
-		serviceList = append(serviceList, svc.Name())
+	for _, info := range s.registry.serviceInfos() {
+		if !info.Enabled {
+			continue
+		}
+		if info.Description != "" {
+			serviceList = append(serviceList, fmt.Sprintf("%s: %s", info.Name, info.Description))
+		} else {
+			serviceList = append(serviceList, info.Name)
+		}
 	}
 	return serviceList
 }
 
-// listToolsForService returns tools filtered by service name.
+// listToolsForService returns tools filtered by service name, sorted by name.
 func (s *Server) listToolsForService(serviceName string) []string {
 	var toolList []string
-	for _, tool := range s.tools {
-		if strings.ToLower(tool.ServiceName) == strings.ToLower(serviceName) {
+	for _, tool := range s.registry.snapshot() {
+		if strings.EqualFold(tool.ServiceName, serviceName) {
 			toolList = append(toolList, fmt.Sprintf("%s: %s", tool.Name, tool.Description))
 		}
 	}
 	return toolList
 }
 
+// extractServiceName finds a registered service named in a lower-cased
+// instruction, falling back to the built-in service names.
+func (s *Server) extractServiceName(instruction string) (string, bool) {
+	for _, info := range s.registry.serviceInfos() {
+		if strings.Contains(instruction, strings.ToLower(info.Name)) {
+			return info.Name, true
+		}
+	}
+	return utils.ExtractServiceName(instruction)
+}
+
 // ProcessInstruction handles a plain language instruction.
 func (s *Server) ProcessInstruction(instruction *string, reply *mcp.RPCResponse) error {
+	return s.processInstruction(context.Background(), instruction, reply)
+}
+
+// processInstruction handles a plain language instruction until ctx is done.
+func (s *Server) processInstruction(ctx context.Context, instruction *string, reply *mcp.RPCResponse) error {
 	logger.Debugf("Entering ProcessInstruction with instruction: %s", *instruction)
 	defer logger.Debug("Exiting ProcessInstruction")
 
@@ -164,7 +383,7 @@ func (s *Server) ProcessInstruction(instruction *string, reply *mcp.RPCResponse)
 
 	// If the query asks for tools, optionally extract a service name.
 	if utils.IsListToolsQuery(lowerInst) {
-		if serviceName, found := utils.ExtractServiceName(lowerInst); found {
+		if serviceName, found := s.extractServiceName(lowerInst); found {
 			tools := s.listToolsForService(serviceName)
 			res, err := json.Marshal(tools)
 			if err != nil {
@@ -188,12 +407,12 @@ func (s *Server) ProcessInstruction(instruction *string, reply *mcp.RPCResponse)
 	defer utils.ClearSystemPromptOverride()
 
 	var plan string
-	if err := s.CallLLM(instruction, &plan); err != nil {
+	if err := s.callLLM(ctx, instruction, &plan); err != nil {
 		return fmt.Errorf("ProcessInstruction: failed to call LLM: %w", err)
 	}
 
 	logger.Debugf("[ProcessInstruction] Generated plan: %s", plan)
-	if err := s.ExecutePlan(&plan, reply); err != nil {
+	if err := s.executePlan(ctx, &plan, reply); err != nil {
 		return fmt.Errorf("ProcessInstruction: failed to execute plan: %w", err)
 	}
 
@@ -201,11 +420,11 @@ func (s *Server) ProcessInstruction(instruction *string, reply *mcp.RPCResponse)
 }
 
 // invokeTool executes a tool based on the LLM function call.
-func (s *Server) invokeTool(functionCall *llms.FunctionCall) (string, error) {
+func (s *Server) invokeTool(ctx context.Context, functionCall *llms.FunctionCall) (string, error) {
 	logger.Debugf("Entering invokeTool for function: %s", functionCall.Name)
 	defer logger.Debug("Exiting invokeTool")
 
-	tool, exists := s.tools[functionCall.Name]
+	tool, exists := s.registry.tool(functionCall.Name)
 	if !exists {
 		return "", fmt.Errorf("Tool %s not found", functionCall.Name)
 	}
@@ -214,11 +433,13 @@ func (s *Server) invokeTool(functionCall *llms.FunctionCall) (string, error) {
 	if err := json.Unmarshal([]byte(functionCall.Arguments), &params); err != nil {
 		return "", fmt.Errorf("invalid arguments for tool %s: %v", functionCall.Name, err)
 	}
+	if err := checkRoots(ctx, tool, params); err != nil {
+		return "", fmt.Errorf("invalid arguments for tool %s: %v", functionCall.Name, err)
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	ctx = withLogger(ctx, nil, functionCall.Name)
 
-	result, err := tool.Handler(ctx, params)
+	result, err := s.runTool(ctx, tool, params)
 	if err != nil {
 		return "", fmt.Errorf("error executing tool %s: %v", functionCall.Name, err)
 	}
@@ -228,17 +449,26 @@ func (s *Server) invokeTool(functionCall *llms.FunctionCall) (string, error) {
 
 // CallLLM sends input to the LLM and returns a generated JSON plan.
 func (s *Server) CallLLM(input *string, reply *string) error {
+	return s.callLLM(context.Background(), input, reply)
+}
+
+// callLLM sends input to the LLM and returns a generated JSON plan.
+func (s *Server) callLLM(ctx context.Context, input *string, reply *string) error {
 	logger.Debugf("Entering CallLLM with input: %s", *input)
 	defer logger.Debug("Exiting CallLLM")
 
+	if s.llm == nil {
+		return s.sampleLLM(ctx, input, reply)
+	}
+
 	var registeredTools []llms.Tool
-	for _, tool := range s.tools {
+	for _, tool := range s.registry.snapshot() {
 		registeredTools = append(registeredTools, llms.Tool{
 			Type: "function",
 			Function: &llms.FunctionDefinition{
 				Name:        tool.Name,
 				Description: tool.Description,
-				Parameters:  tool.InputSchema,
+				Parameters:  toolDefinition(tool).InputSchema,
 			},
 		})
 	}
@@ -249,7 +479,7 @@ func (s *Server) CallLLM(input *string, reply *string) error {
 	}
 
 	response, err := s.llm.GenerateContent(
-		context.Background(),
+		ctx,
 		prompt,
 		llms.WithTools(registeredTools),
 		llms.WithJSONMode(),
@@ -272,7 +502,7 @@ func (s *Server) CallLLM(input *string, reply *string) error {
 		for _, toolCall := range toolCalls {
 			if toolCall.FunctionCall != nil {
 				logger.Debugf("[Tool Invoked] Function: %s, Arguments: %s", toolCall.FunctionCall.Name, toolCall.FunctionCall.Arguments)
-				if result, err := s.invokeTool(toolCall.FunctionCall); err == nil {
+				if result, err := s.invokeTool(ctx, toolCall.FunctionCall); err == nil {
 					*reply = result
 					return nil
 				} else {
@@ -287,14 +517,35 @@ func (s *Server) CallLLM(input *string, reply *string) error {
 	return nil
 }
 
+// sampleLLM asks the client's model for a JSON plan through MCP sampling. The
+// client cannot call tools on our behalf, so the plan is executed by executePlan.
+func (s *Server) sampleLLM(ctx context.Context, input *string, reply *string) error {
+	result, err := plugins.SamplerFromContext(ctx).CreateMessage(ctx, plugins.SamplingRequest{
+		Messages:     []plugins.SamplingMessage{{Role: "user", Text: *input}},
+		SystemPrompt: utils.GetSystemPrompt(),
+	})
+	if err != nil {
+		logger.Errorf("[CallLLM] Sampling error: %v", err)
+		return fmt.Errorf("LLM sampling error: %w", err)
+	}
+	logger.Debugf("[CallLLM] Sampled response from %s: %q", result.Model, result.Text)
+	*reply = result.Text
+	return nil
+}
+
 // ExecutePlan processes the JSON plan generated by the LLM.
 func (s *Server) ExecutePlan(planJSON *string, reply *mcp.RPCResponse) error {
+	return s.executePlan(context.Background(), planJSON, reply)
+}
+
+// executePlan processes the JSON plan generated by the LLM until ctx is done.
+func (s *Server) executePlan(ctx context.Context, planJSON *string, reply *mcp.RPCResponse) error {
 	logger.Debugf("Entering ExecutePlan with plan: %s", *planJSON)
 	defer logger.Debug("Exiting ExecutePlan")
 
 	response := mcp.RPCResponse{Version: mcp.JSONRPCVersion}
 	if planJSON == nil || *planJSON == "" {
-		response.Error = mcp.NewError(-32602, "ExecutePlan received empty plan")
+		response.Error = mcp.NewError(mcp.InvalidParams, "ExecutePlan received empty plan")
 		*reply = response
 		return nil
 	}
@@ -304,7 +555,7 @@ func (s *Server) ExecutePlan(planJSON *string, reply *mcp.RPCResponse) error {
 	var raw interface{}
 	if err := json.Unmarshal([]byte(*planJSON), &raw); err != nil {
 		logger.Errorf("[ExecutePlan] Error unmarshalling JSON: %v", err)
-		response.Error = mcp.NewError(-32700, fmt.Sprintf("failed to parse plan JSON: %v", err))
+		response.Error = mcp.NewError(mcp.ParseError, fmt.Sprintf("failed to parse plan JSON: %v", err))
 		*reply = response
 		return nil
 	}
@@ -316,7 +567,7 @@ func (s *Server) ExecutePlan(planJSON *string, reply *mcp.RPCResponse) error {
 			if m, ok := elem.(map[string]interface{}); ok {
 				plan = append(plan, m)
 			} else {
-				response.Error = mcp.NewError(-32700, "plan array contains non-object element")
+				response.Error = mcp.NewError(mcp.ParseError, "plan array contains non-object element")
 				*reply = response
 				return nil
 			}
@@ -324,26 +575,23 @@ func (s *Server) ExecutePlan(planJSON *string, reply *mcp.RPCResponse) error {
 	case map[string]interface{}:
 		plan = []map[string]interface{}{v}
 	default:
-		response.Error = mcp.NewError(-32700, "plan JSON is neither an object nor an array")
+		response.Error = mcp.NewError(mcp.ParseError, "plan JSON is neither an object nor an array")
 		*reply = response
 		return nil
 	}
 
 	if len(plan) == 0 {
 		logger.Errorf("[ExecutePlan] No actions found in plan")
-		response.Error = mcp.NewError(-32602, "received empty plan from LLM")
+		response.Error = mcp.NewError(mcp.InvalidParams, "received empty plan from LLM")
 		*reply = response
 		return nil
 	}
 
-	_, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
 	for _, action := range plan {
 		logger.Debugf("[ExecutePlan] Processing action: %+v", action)
 		actionType, ok := action["action"].(string)
 		if !ok || actionType == "" {
-			response.Error = mcp.NewError(-32602, "invalid action format")
+			response.Error = mcp.NewError(mcp.InvalidParams, "invalid action format")
 			*reply = response
 			return nil
 		}
@@ -352,16 +600,21 @@ func (s *Server) ExecutePlan(planJSON *string, reply *mcp.RPCResponse) error {
 		}
 
 		parameters, _ := action["parameters"].(map[string]interface{})
-		if tool, exists := s.tools[actionType]; exists {
-			result, err := tool.Handler(context.Background(), parameters)
+		if tool, exists := s.registry.tool(actionType); exists {
+			if err := checkRoots(ctx, tool, parameters); err != nil {
+				response.Error = mcp.NewError(mcp.InvalidParams, fmt.Sprintf("invalid parameters for tool %s: %v", actionType, err))
+				*reply = response
+				return nil
+			}
+			result, err := s.runTool(withLogger(ctx, nil, actionType), tool, parameters)
 			if err != nil {
-				response.Error = mcp.NewError(-32000, fmt.Sprintf("failed to execute tool %s: %v", actionType, err))
+				response.Error = mcp.NewError(mcp.ServerError, fmt.Sprintf("failed to execute tool %s: %v", actionType, err))
 				*reply = response
 				return nil
 			}
 			logger.Debugf("[ExecutePlan] Tool %s result: %v", actionType, result)
 		} else {
-			response.Error = mcp.NewError(-32601, fmt.Sprintf("unknown action: %s", actionType))
+			response.Error = mcp.NewError(mcp.MethodNotFound, fmt.Sprintf("unknown action: %s", actionType))
 			*reply = response
 			return nil
 		}
@@ -372,7 +625,7 @@ func (s *Server) ExecutePlan(planJSON *string, reply *mcp.RPCResponse) error {
 		"message": "Plan executed successfully",
 	})
 	if err != nil {
-		response.Error = mcp.NewError(-32000, fmt.Sprintf("failed to marshal result: %v", err))
+		response.Error = mcp.NewError(mcp.ServerError, fmt.Sprintf("failed to marshal result: %v", err))
 	} else {
 		response.Result = json.RawMessage(resultJSON)
 	}
@@ -382,23 +635,33 @@ func (s *Server) ExecutePlan(planJSON *string, reply *mcp.RPCResponse) error {
 
 // CallTool allows direct invocation of a tool.
 func (s *Server) CallTool(args *mcp.ToolCallArgs, reply *mcp.RPCResponse) error {
+	return s.callTool(context.Background(), args, reply)
+}
+
+// callTool invokes a tool directly, cancelling it when ctx is done.
+func (s *Server) callTool(ctx context.Context, args *mcp.ToolCallArgs, reply *mcp.RPCResponse) error {
 	logger.Debugf("Entering CallTool for tool: %s", args.ToolName)
 	defer logger.Debug("Exiting CallTool")
 
 	response := mcp.RPCResponse{Version: mcp.JSONRPCVersion}
-	tool, exists := s.tools[args.ToolName]
+	tool, exists := s.registry.tool(args.ToolName)
 	if !exists {
-		response.Error = mcp.NewError(-32601, fmt.Sprintf("unknown tool: %s", args.ToolName))
+		response.Error = mcp.NewError(mcp.MethodNotFound, fmt.Sprintf("unknown tool: %s", args.ToolName))
 		*reply = response
 		return nil
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	if err := checkRoots(ctx, tool, args.Parameters); err != nil {
+		response.Error = mcp.NewError(mcp.InvalidParams, fmt.Sprintf("invalid parameters for tool %s: %v", args.ToolName, err))
+		*reply = response
+		return nil
+	}
+
+	ctx = withLogger(ctx, nil, args.ToolName)
 
-	result, err := tool.Handler(ctx, args.Parameters)
+	result, err := s.runTool(ctx, tool, args.Parameters)
 	if err != nil {
-		response.Error = mcp.NewError(-32000, fmt.Sprintf("failed to execute tool %s: %v", args.ToolName, err))
+		response.Error = mcp.NewError(mcp.ServerError, fmt.Sprintf("failed to execute tool %s: %v", args.ToolName, err))
 		*reply = response
 		return nil
 	}
@@ -409,7 +672,7 @@ func (s *Server) CallTool(args *mcp.ToolCallArgs, reply *mcp.RPCResponse) error
 		"result":  result,
 	})
 	if err != nil {
-		response.Error = mcp.NewError(-32000, fmt.Sprintf("failed to marshal result: %v", err))
+		response.Error = mcp.NewError(mcp.ServerError, fmt.Sprintf("failed to marshal result: %v", err))
 	} else {
 		response.Result = json.RawMessage(resultJSON)
 	}
@@ -417,25 +680,17 @@ func (s *Server) CallTool(args *mcp.ToolCallArgs, reply *mcp.RPCResponse) error
 	return nil
 }
 
-// httpReadWriteCloser adapts HTTP request/response to io.ReadWriteCloser.
-type httpReadWriteCloser struct {
-	r io.ReadCloser
-	w io.Writer
+// looksLikeJSON reports whether data is a JSON object or array rather than a
+// plain text instruction.
+func looksLikeJSON(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
 }
 
-func (hrwc *httpReadWriteCloser) Read(p []byte) (int, error) { return hrwc.r.Read(p) }
-
-func (hrwc *httpReadWriteCloser) Write(p []byte) (int, error) { return hrwc.w.Write(p) }
-
-func (hrwc *httpReadWriteCloser) Close() error { return hrwc.r.Close() }
-
-// StartRPCServer starts the JSON-RPC server on port 1234.
+// StartRPCServer starts the JSON-RPC server on the configured address.
 func (s *Server) StartRPCServer() {
-	logger.Infof("Starting JSON-RPC server on port 1234 (POST /rpc)...")
-	rpcServer := rpc.NewServer()
-	if err := rpcServer.RegisterName("Server", s); err != nil {
-		logger.Fatalf("Failed to register RPC service: %v", err)
-	}
+	address := s.Settings().Address
+	logger.Infof("Starting JSON-RPC server on %s (POST /rpc)...", address)
 
 	http.HandleFunc("/rpc", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -447,18 +702,20 @@ func (s *Server) StartRPCServer() {
 			http.Error(w, "Failed to read request", http.StatusBadRequest)
 			return
 		}
-		var req mcp.RPCRequest
-		if err := json.Unmarshal(data, &req); err == nil && req.Method != "" {
-			codec := jsonrpc.NewServerCodec(&httpReadWriteCloser{
-				r: io.NopCloser(bytes.NewBuffer(data)),
-				w: w,
-			})
-			rpcServer.ServeRequest(codec)
+		if looksLikeJSON(data) {
+			// Each request gets its own session, so clients cannot cancel
+			// or change the log level of each other's requests.
+			reply := s.handleMessage(r.Context(), newStatelessSession(), data)
+			if reply == nil {
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			writeJSON(w, http.StatusOK, reply)
 		} else {
 			instruction := string(data)
 			logger.Debugf("Received plain text instruction: %s", instruction)
 			var reply mcp.RPCResponse
-			if err := s.ProcessInstruction(&instruction, &reply); err != nil {
+			if err := s.processInstruction(r.Context(), &instruction, &reply); err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
@@ -472,6 +729,9 @@ func (s *Server) StartRPCServer() {
 		}
 	})
 
-	logger.Infof("JSON-RPC server listening on port 1234 (POST /rpc)...")
-	logger.Fatal(http.ListenAndServe(":1234", nil))
+	http.HandleFunc("/mcp", s.handleStreamableHTTP)
+	go s.expireSessions()
+
+	logger.Infof("JSON-RPC server listening on %s (POST /rpc, Streamable HTTP /mcp)...", address)
+	logger.Fatal(http.ListenAndServe(address, nil))
 }
@@ -0,0 +1,383 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/santoshkal/gomcp/pkg/mcp"
+	"github.com/santoshkal/gomcp/pkg/plugins"
+)
+
+// errNoStream is returned when a message cannot be delivered because the client
+// has no open stream.
+var errNoStream = errors.New("session has no open stream to the client")
+
+// messageSender delivers a single JSON-RPC message to the client.
+type messageSender func(msg interface{}) error
+
+// Session holds the per-client state negotiated during the MCP handshake.
+type Session struct {
+	id        string
+	stateless bool // serves a single /rpc request
+
+	mu              sync.Mutex
+	initializing    bool // initialize request handled
+	initialized     bool // notifications/initialized received
+	protocolVersion string
+	clientInfo      mcp.Implementation
+	clientCaps      mcp.ClientCapabilities
+	sender          messageSender // nil while the client has no open stream
+	inflight        map[string]*inflightRequest
+	subscriptions   map[string]bool // resource URIs
+	logLevel        mcp.LoggingLevel
+	nextRequestID   int64
+	pending         map[string]chan *inboundMessage // server requests awaiting a response
+	roots           []plugins.Root
+	rootsKnown      bool          // roots were listed by the client
+	rootsFetching   chan struct{} // closed when the roots/list in flight completes
+	lastActive      time.Time     // last request or stream of the client
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newSession returns a session that has not yet completed the handshake.
+func newSession(id string) *Session {
+	return &Session{id: id, done: make(chan struct{}), inflight: make(map[string]*inflightRequest), subscriptions: make(map[string]bool), pending: make(map[string]chan *inboundMessage), logLevel: defaultClientLogLevel, lastActive: time.Now()}
+}
+
+// newStatelessSession returns a session for a single request to the /rpc
+// endpoint. The endpoint keeps no state between requests, so the session needs
+// no handshake and has no stream to the client.
+func newStatelessSession() *Session {
+	sess := newSession("rpc-" + newSessionID())
+	sess.stateless = true
+	sess.initializing = true
+	return sess
+}
+
+// inflightRequest tracks a request that is still being handled.
+type inflightRequest struct {
+	cancel    context.CancelFunc
+	cancelled bool // cancelled by notifications/cancelled
+}
+
+// track registers an in-flight request and returns a context that is cancelled
+// when the client cancels it, along with a function that stops tracking.
+func (sess *Session) track(ctx context.Context, id mcp.ID) (context.Context, *inflightRequest, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	req := &inflightRequest{cancel: cancel}
+	key := id.String()
+
+	sess.mu.Lock()
+	sess.inflight[key] = req
+	sess.mu.Unlock()
+
+	return ctx, req, func() {
+		sess.mu.Lock()
+		if sess.inflight[key] == req {
+			delete(sess.inflight, key)
+		}
+		sess.mu.Unlock()
+		cancel()
+	}
+}
+
+// cancelRequest cancels the in-flight request with the given ID, if any.
+func (sess *Session) cancelRequest(id mcp.ID) bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	req, ok := sess.inflight[id.String()]
+	if !ok {
+		return false
+	}
+	req.cancelled = true
+	req.cancel()
+	return true
+}
+
+// wasCancelled reports whether the client cancelled req.
+func (sess *Session) wasCancelled(req *inflightRequest) bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return req.cancelled
+}
+
+// newSessionID returns a random, URL-safe session identifier.
+func newSessionID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to generate session ID: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// ID returns the session identifier.
+func (sess *Session) ID() string {
+	return sess.id
+}
+
+// setSender installs the stream used for messages that are not tied to a request.
+func (sess *Session) setSender(fn messageSender) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.sender = fn
+}
+
+// claimSender installs fn as the session stream unless one is already open.
+func (sess *Session) claimSender(fn messageSender) bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.sender != nil {
+		return false
+	}
+	sess.sender = fn
+	return true
+}
+
+// Notify sends a notification to the client over the session's stream.
+func (sess *Session) Notify(method string, params interface{}) error {
+	sess.mu.Lock()
+	sender := sess.sender
+	sess.mu.Unlock()
+	if sender == nil {
+		return errNoStream
+	}
+	msg, err := mcp.NewNotification(method, params)
+	if err != nil {
+		return err
+	}
+	return sender(msg)
+}
+
+// touch records activity of the client.
+func (sess *Session) touch() {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.lastActive = time.Now()
+}
+
+// idleSince returns when the client was last active, and false while it has
+// an open stream or a request in flight.
+func (sess *Session) idleSince() (time.Time, bool) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.sender != nil || len(sess.inflight) > 0 {
+		return time.Time{}, false
+	}
+	return sess.lastActive, true
+}
+
+// close terminates the session and any stream waiting on it.
+func (sess *Session) close() {
+	sess.closeOnce.Do(func() { close(sess.done) })
+}
+
+// senderKey is the context key for the stream of the request being handled.
+type senderKey struct{}
+
+// withSender returns a context whose request-scoped messages are sent with fn.
+func withSender(ctx context.Context, fn messageSender) context.Context {
+	return context.WithValue(ctx, senderKey{}, fn)
+}
+
+// send delivers a message related to the request in ctx. It prefers the
+// request's own stream and falls back to the session stream.
+func send(ctx context.Context, sess *Session, msg interface{}) error {
+	if sender, ok := ctx.Value(senderKey{}).(messageSender); ok && sender != nil {
+		return sender(msg)
+	}
+	sess.mu.Lock()
+	sender := sess.sender
+	sess.mu.Unlock()
+	if sender == nil {
+		return errNoStream
+	}
+	return sender(msg)
+}
+
+// notify sends a notification related to the request in ctx.
+func notify(ctx context.Context, sess *Session, method string, params interface{}) error {
+	msg, err := mcp.NewNotification(method, params)
+	if err != nil {
+		return err
+	}
+	return send(ctx, sess, msg)
+}
+
+// request sends a request to the client and decodes its result into result. If
+// ctx is done first, the client is told to cancel the request.
+func request(ctx context.Context, sess *Session, method string, params, result interface{}) error {
+	ch := make(chan *inboundMessage, 1)
+	sess.mu.Lock()
+	sess.nextRequestID++
+	id := mcp.ID(strconv.FormatInt(sess.nextRequestID, 10))
+	key := id.String()
+	sess.pending[key] = ch
+	sess.mu.Unlock()
+	defer func() {
+		sess.mu.Lock()
+		delete(sess.pending, key)
+		sess.mu.Unlock()
+	}()
+
+	req, err := mcp.NewRequest(id, method, params)
+	if err != nil {
+		return err
+	}
+	if err := send(ctx, sess, req); err != nil {
+		return err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return fmt.Errorf("%s failed: %s (code %d)", method, resp.Error.Message, resp.Error.Code)
+		}
+		if result == nil {
+			return nil
+		}
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("invalid %s result: %w", method, err)
+		}
+		return nil
+	case <-ctx.Done():
+		if err := notify(context.Background(), sess, mcp.NotificationCancelled, mcp.CancelledParams{RequestID: id, Reason: ctx.Err().Error()}); err != nil {
+			logger.Debugf("failed to cancel %s request %s: %v", method, key, err)
+		}
+		return ctx.Err()
+	case <-sess.done:
+		return errors.New("session closed")
+	}
+}
+
+// deliver hands a client response to the server request awaiting it.
+func (sess *Session) deliver(msg *inboundMessage) bool {
+	sess.mu.Lock()
+	ch, ok := sess.pending[msg.ID.String()]
+	delete(sess.pending, msg.ID.String())
+	sess.mu.Unlock()
+	if ok {
+		ch <- msg
+	}
+	return ok
+}
+
+// ProtocolVersion returns the negotiated protocol version, or "" before initialize.
+func (sess *Session) ProtocolVersion() string {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.protocolVersion
+}
+
+// ClientInfo returns the name and version reported by the client.
+func (sess *Session) ClientInfo() mcp.Implementation {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.clientInfo
+}
+
+// ClientCapabilities returns the capabilities declared by the client.
+func (sess *Session) ClientCapabilities() mcp.ClientCapabilities {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.clientCaps
+}
+
+// LogLevel returns the minimum level of log messages sent to the client.
+func (sess *Session) LogLevel() mcp.LoggingLevel {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.logLevel
+}
+
+// Initialized reports whether the client has acknowledged the handshake.
+func (sess *Session) Initialized() bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.initialized
+}
+
+// ready reports whether the initialize request has been handled for this session.
+func (sess *Session) ready() bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.initializing || sess.initialized
+}
+
+// subscribe records interest in updates to the resource at uri.
+func (sess *Session) subscribe(uri string) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.subscriptions[uri] = true
+}
+
+// unsubscribe removes a subscription made with subscribe.
+func (sess *Session) unsubscribe(uri string) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	delete(sess.subscriptions, uri)
+}
+
+// subscribed reports whether the session wants updates to the resource at uri.
+func (sess *Session) subscribed(uri string) bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.subscriptions[uri]
+}
+
+// addSession tracks a connected session.
+func (s *Server) addSession(sess *Session) {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	s.sessions[sess.ID()] = sess
+}
+
+// session returns the connected session with the given ID.
+func (s *Server) session(id string) (*Session, bool) {
+	s.sessionsMu.RLock()
+	defer s.sessionsMu.RUnlock()
+	sess, ok := s.sessions[id]
+	return sess, ok
+}
+
+// removeSession forgets and closes the session with the given ID.
+func (s *Server) removeSession(id string) {
+	s.sessionsMu.Lock()
+	sess, ok := s.sessions[id]
+	delete(s.sessions, id)
+	s.sessionsMu.Unlock()
+	if ok {
+		sess.close()
+	}
+}
+
+// sessionList returns a snapshot of the connected sessions.
+func (s *Server) sessionList() []*Session {
+	s.sessionsMu.RLock()
+	defer s.sessionsMu.RUnlock()
+	list := make([]*Session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		list = append(list, sess)
+	}
+	return list
+}
+
+// broadcast sends a notification to every initialized session with an open stream.
+func (s *Server) broadcast(method string, params interface{}) {
+	for _, sess := range s.sessionList() {
+		if !sess.ready() {
+			continue
+		}
+		if err := sess.Notify(method, params); err != nil {
+			logger.Debugf("failed to send %s to session %s: %v", method, sess.ID(), err)
+		}
+	}
+}
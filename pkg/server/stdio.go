@@ -0,0 +1,90 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// maxStdioMessageSize bounds a single newline-delimited message read from stdin.
+const maxStdioMessageSize = 16 * 1024 * 1024
+
+// stdioConn serializes writes of newline-delimited JSON messages.
+type stdioConn struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// write encodes msg as a single line of JSON.
+func (c *stdioConn) write(msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+// ServeStdio serves MCP over newline-delimited JSON-RPC read from r and written to w.
+// It returns when r reaches EOF or ctx is cancelled. Nothing but protocol messages
+// may be written to w, so callers must keep logs off stdout.
+func (s *Server) ServeStdio(ctx context.Context, r io.Reader, w io.Writer) error {
+	logger.Infof("Serving MCP over stdio...")
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	conn := &stdioConn{w: w}
+	sess := newSession(newSessionID())
+	sess.setSender(conn.write)
+	s.addSession(sess)
+	defer s.removeSession(sess.ID())
+
+	// Cancel in-flight handlers once the client goes away.
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxStdioMessageSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		data := append([]byte(nil), line...)
+		serve := func() {
+			reply := s.handleMessage(ctx, sess, data)
+			if reply == nil {
+				return
+			}
+			if err := conn.write(reply); err != nil {
+				logger.Errorf("[ServeStdio] %v", err)
+			}
+		}
+
+		// Requests run concurrently so that a later notifications/cancelled can
+		// reach them. Notifications, the handshake and anything that arrives
+		// before it are handled in order, so no request can overtake initialize.
+		if !sess.ready() || isInitializeRequest(data) || !expectsReplyPayload(data) {
+			serve()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serve()
+		}()
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read from stdin: %w", err)
+	}
+	logger.Infof("Stdio client disconnected")
+	return nil
+}
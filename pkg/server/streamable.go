@@ -0,0 +1,317 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/santoshkal/gomcp/pkg/mcp"
+)
+
+// sessionHeader carries the Streamable HTTP session ID.
+const sessionHeader = "Mcp-Session-Id"
+
+// sseKeepAlive is the interval between keep-alive comments on idle event streams.
+const sseKeepAlive = 15 * time.Second
+
+// errStreamClosed is returned when writing to an event stream that has ended.
+var errStreamClosed = errors.New("event stream closed")
+
+// sseStream writes JSON-RPC messages as server-sent events.
+type sseStream struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+	closed  bool
+}
+
+// newSSEStream returns an event stream over w. Nothing is written until open.
+func newSSEStream(w http.ResponseWriter) (*sseStream, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, fmt.Errorf("response writer does not support streaming")
+	}
+	return &sseStream{w: w, flusher: flusher}, nil
+}
+
+// open writes the event stream headers.
+func (st *sseStream) open() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.w.Header().Set("Content-Type", "text/event-stream")
+	st.w.Header().Set("Cache-Control", "no-cache")
+	st.w.Header().Set("Connection", "keep-alive")
+	st.w.WriteHeader(http.StatusOK)
+	st.flusher.Flush()
+}
+
+// send writes msg as a single "message" event.
+func (st *sseStream) send(msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.closed {
+		return errStreamClosed
+	}
+	if _, err := fmt.Fprintf(st.w, "event: message\ndata: %s\n\n", data); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+	st.flusher.Flush()
+	return nil
+}
+
+// keepAlive writes an SSE comment so intermediaries do not drop an idle stream.
+func (st *sseStream) keepAlive() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.closed {
+		return
+	}
+	fmt.Fprint(st.w, ": keep-alive\n\n")
+	st.flusher.Flush()
+}
+
+// close stops further writes; it must be called before the handler returns.
+func (st *sseStream) close() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.closed = true
+}
+
+// acceptsEventStream reports whether the client accepts server-sent events.
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// handleStreamableHTTP serves the MCP Streamable HTTP transport.
+func (s *Server) handleStreamableHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.allowedOrigin(r) {
+		logger.Warnf("Rejected Streamable HTTP request from origin %s", r.Header.Get("Origin"))
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		s.handleStreamablePost(w, r)
+	case http.MethodGet:
+		s.handleStreamableGet(w, r)
+	case http.MethodDelete:
+		s.handleStreamableDelete(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// allowedOrigin reports whether r may use the Streamable HTTP endpoint.
+// Requests without an Origin header do not come from a browser and are
+// allowed. Browser origins must be loopback ones or listed in
+// server.allowed_origins, so that web pages cannot reach the server through
+// DNS rebinding.
+func (s *Server) allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range s.Settings().AllowedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if host := u.Hostname(); host != "localhost" {
+		ip := net.ParseIP(host)
+		return ip != nil && ip.IsLoopback()
+	}
+	return true
+}
+
+// sessionSweepInterval is how often idle Streamable HTTP sessions are expired,
+// unless their timeout is shorter.
+const sessionSweepInterval = time.Minute
+
+// expireSessions removes Streamable HTTP sessions that stayed idle for longer
+// than server.timeouts.session, so that clients which never send DELETE do
+// not leak them. It runs until the server exits.
+func (s *Server) expireSessions() {
+	for {
+		timeout := s.timeouts().Session
+		time.Sleep(min(timeout, sessionSweepInterval))
+		now := time.Now()
+		for _, sess := range s.sessionList() {
+			if since, idle := sess.idleSince(); idle && now.Sub(since) > timeout {
+				s.removeSession(sess.ID())
+				logger.Infof("Expired Streamable HTTP session %s after %v idle", sess.ID(), timeout)
+			}
+		}
+	}
+}
+
+// handleStreamablePost handles a client message. Requests are answered with a
+// single JSON response, or with an event stream when the client accepts one so
+// that notifications raised while handling the request reach the client first.
+func (s *Server) handleStreamablePost(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request", http.StatusBadRequest)
+		return
+	}
+
+	msgs, batch, err := mcp.DecodeBatch(data)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, &mcp.RPCResponse{
+			Version: mcp.JSONRPCVersion,
+			Error:   mcp.NewError(mcp.ParseError, fmt.Sprintf("parse error: %v", err)),
+		})
+		return
+	}
+
+	var initialize, hasRequests bool
+	for _, raw := range msgs {
+		hasRequests = hasRequests || expectsReply(raw)
+		initialize = initialize || isInitializeRequest(raw)
+	}
+	if initialize && batch {
+		writeJSON(w, http.StatusBadRequest, &mcp.RPCResponse{
+			Version: mcp.JSONRPCVersion,
+			Error:   mcp.NewError(mcp.InvalidRequest, "invalid request: initialize must not be part of a batch"),
+		})
+		return
+	}
+
+	var sess *Session
+	if initialize {
+		sess = newSession(newSessionID())
+		s.addSession(sess)
+		logger.Infof("Created Streamable HTTP session %s", sess.ID())
+	} else {
+		id := r.Header.Get(sessionHeader)
+		if id == "" {
+			http.Error(w, "missing "+sessionHeader+" header", http.StatusBadRequest)
+			return
+		}
+		var ok bool
+		if sess, ok = s.session(id); !ok {
+			http.Error(w, "unknown session", http.StatusNotFound)
+			return
+		}
+		sess.touch()
+		defer sess.touch()
+	}
+
+	// Notifications and responses are acknowledged without a body.
+	if !hasRequests {
+		s.handleMessage(r.Context(), sess, data)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if initialize || !acceptsEventStream(r) {
+		reply := s.handleMessage(r.Context(), sess, data)
+		if initialize {
+			if response, ok := reply.(*mcp.RPCResponse); ok && response.Error == nil {
+				w.Header().Set(sessionHeader, sess.ID())
+			} else {
+				s.removeSession(sess.ID())
+			}
+		}
+		writeJSON(w, http.StatusOK, reply)
+		return
+	}
+
+	stream, err := newSSEStream(w)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	stream.open()
+	defer stream.close()
+
+	reply := s.handleMessage(withSender(r.Context(), stream.send), sess, data)
+	if err := stream.send(reply); err != nil {
+		logger.Errorf("[handleStreamablePost] %v", err)
+	}
+}
+
+// handleStreamableGet opens the event stream used for messages that are not tied
+// to a client request, such as list-changed notifications.
+func (s *Server) handleStreamableGet(w http.ResponseWriter, r *http.Request) {
+	if !acceptsEventStream(r) {
+		http.Error(w, "GET requires Accept: text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+	sess, ok := s.session(r.Header.Get(sessionHeader))
+	if !ok {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+
+	stream, err := newSSEStream(w)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !sess.claimSender(stream.send) {
+		http.Error(w, "session already has an open event stream", http.StatusConflict)
+		return
+	}
+	defer sess.touch()
+	defer sess.setSender(nil)
+	stream.open()
+	defer stream.close()
+	logger.Debugf("Opened event stream for session %s", sess.ID())
+
+	// Roots could not be listed while the client had no stream.
+	go s.ensureRoots(r.Context(), sess)
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			logger.Debugf("Event stream for session %s closed by client", sess.ID())
+			return
+		case <-sess.done:
+			return
+		case <-ticker.C:
+			stream.keepAlive()
+		}
+	}
+}
+
+// handleStreamableDelete terminates a session at the client's request.
+func (s *Server) handleStreamableDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(sessionHeader)
+	if _, ok := s.session(id); !ok {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	s.removeSession(id)
+	logger.Infof("Terminated Streamable HTTP session %s", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeJSON writes v as a JSON response body with the given status.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
@@ -0,0 +1,229 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/santoshkal/gomcp/pkg/mcp"
+	"github.com/santoshkal/gomcp/pkg/plugins"
+)
+
+// listPageSize is the maximum number of entries returned by a single list request.
+const listPageSize = 50
+
+// handleToolsList returns a page of registered tools sorted by name.
+func (s *Server) handleToolsList(ctx context.Context, sess *Session, params json.RawMessage) (interface{}, *mcp.RPCError) {
+	var p mcp.PaginatedParams
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, mcp.NewError(mcp.InvalidParams, fmt.Sprintf("invalid tools/list params: %v", err))
+		}
+	}
+
+	tools := s.registry.snapshot()
+	names := make([]string, 0, len(tools))
+	byName := make(map[string]RegisteredTool, len(tools))
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+		byName[tool.Name] = tool
+	}
+
+	page, next, err := paginate(names, p.Cursor)
+	if err != nil {
+		return nil, mcp.NewError(mcp.InvalidParams, err.Error())
+	}
+
+	result := mcp.ListToolsResult{Tools: make([]mcp.Tool, 0, len(page)), NextCursor: next}
+	for _, name := range page {
+		result.Tools = append(result.Tools, toolDefinition(byName[name]))
+	}
+	return result, nil
+}
+
+// handleToolsCall invokes a registered tool and wraps its result in MCP content.
+func (s *Server) handleToolsCall(ctx context.Context, sess *Session, params json.RawMessage) (interface{}, *mcp.RPCError) {
+	var p mcp.CallToolParams
+	if len(params) == 0 {
+		return nil, mcp.NewError(mcp.InvalidParams, "tools/call requires params")
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, mcp.NewError(mcp.InvalidParams, fmt.Sprintf("invalid tools/call params: %v", err))
+	}
+
+	tool, exists := s.registry.tool(p.Name)
+	if !exists {
+		return nil, mcp.NewError(mcp.InvalidParams, fmt.Sprintf("unknown tool: %s", p.Name))
+	}
+	if p.Arguments == nil {
+		p.Arguments = map[string]interface{}{}
+	}
+	if err := checkRoots(ctx, tool, p.Arguments); err != nil {
+		return nil, mcp.NewError(mcp.InvalidParams, err.Error())
+	}
+
+	ctx = withProgress(ctx, sess, p.Meta)
+	ctx = withLogger(ctx, sess, p.Name)
+
+	result, err := s.runTool(ctx, tool, p.Arguments)
+	if err != nil {
+		logger.Errorf("[tools/call] Tool %s failed: %v", p.Name, err)
+		return mcp.CallToolResult{
+			Content: []mcp.Content{mcp.NewTextContent(fmt.Sprintf("failed to execute tool %s: %v", p.Name, err))},
+			IsError: true,
+		}, nil
+	}
+
+	content, err := toolContent(result)
+	if err != nil {
+		return nil, mcp.NewError(mcp.InternalError, fmt.Sprintf("failed to marshal result of tool %s: %v", p.Name, err))
+	}
+	return mcp.CallToolResult{Content: content}, nil
+}
+
+// errToolTimeout is returned when an attempt of a tool call runs out of time.
+// It does not wrap the handler's error, so a timed out attempt is never
+// retryable, whatever the handler returned.
+var errToolTimeout = errors.New("tool timed out")
+
+// defaultRetryBackoff is the delay before the first retry of a tool that sets
+// retries but no retry_backoff.
+const defaultRetryBackoff = 500 * time.Millisecond
+
+// runTool invokes a tool within its limits. It waits while MaxConcurrency calls
+// of the tool are running, bounds each attempt by the tool's timeout or the
+// configured default, and retries attempts that failed with an error marked
+// plugins.Retryable, with exponential backoff, until ctx is done. Timeouts are
+// not retried, as the handler may have had its effect. Every invocation path
+// goes through runTool.
+func (s *Server) runTool(ctx context.Context, tool RegisteredTool, params map[string]interface{}) (interface{}, error) {
+	if tool.slots != nil {
+		select {
+		case tool.slots <- struct{}{}:
+			defer func() { <-tool.slots }()
+		case <-ctx.Done():
+			return nil, fmt.Errorf("tool %s is busy with %d calls: %w", tool.Name, cap(tool.slots), ctx.Err())
+		}
+	}
+
+	timeout := tool.Limits.Timeout
+	if timeout == 0 {
+		timeout = s.timeouts().Tool
+	}
+	backoff := tool.Limits.RetryBackoff
+	if backoff == 0 {
+		backoff = defaultRetryBackoff
+	}
+	for attempt := 1; ; attempt++ {
+		result, err := attemptTool(ctx, tool, params, timeout)
+		if err == nil || errors.Is(err, errToolTimeout) || !plugins.IsRetryable(err) || attempt > tool.Limits.Retries || ctx.Err() != nil {
+			return result, err
+		}
+		logger.Warnf("Tool %s failed (attempt %d of %d), retrying in %v: %v", tool.Name, attempt, tool.Limits.Retries+1, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, err
+		}
+		backoff *= 2
+	}
+}
+
+// attemptTool calls the tool's handler once, cancelling it after timeout. A
+// call that failed after its deadline passed returns errToolTimeout.
+func attemptTool(ctx context.Context, tool RegisteredTool, params map[string]interface{}, timeout time.Duration) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result, err := tool.Handler(ctx, params)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("%w after %v: %v", errToolTimeout, timeout, err)
+	}
+	return result, err
+}
+
+// toolDefinition converts a registered tool into its tools/list form. Clients
+// require an object schema, so an empty or untyped schema gets type object.
+func toolDefinition(tool RegisteredTool) mcp.Tool {
+	schema := tool.InputSchema
+	if _, typed := schema["type"]; !typed {
+		schema = make(map[string]interface{}, len(tool.InputSchema)+1)
+		for k, v := range tool.InputSchema {
+			schema[k] = v
+		}
+		schema["type"] = "object"
+	}
+	return mcp.Tool{Name: tool.Name, Description: tool.Description, InputSchema: schema}
+}
+
+// toolContent renders a handler result as content parts. Strings are passed through
+// as text; any other value is encoded as JSON text.
+func toolContent(result interface{}) ([]mcp.Content, error) {
+	switch v := result.(type) {
+	case nil:
+		return []mcp.Content{}, nil
+	case []mcp.Content:
+		return v, nil
+	case mcp.Content:
+		return []mcp.Content{v}, nil
+	case string:
+		return []mcp.Content{mcp.NewTextContent(v)}, nil
+	case []byte:
+		return []mcp.Content{mcp.NewTextContent(string(v))}, nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.Content{mcp.NewTextContent(string(data))}, nil
+}
+
+// paginate returns the page of sorted names following cursor and the cursor of the next page.
+// Cursors encode the last name of the previous page so they survive registry changes.
+func paginate(names []string, cursor string) ([]string, string, error) {
+	start := 0
+	if cursor != "" {
+		last, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid cursor: %q", cursor)
+		}
+		start = sort.SearchStrings(names, string(last))
+		if start < len(names) && names[start] == string(last) {
+			start++
+		}
+	}
+
+	end := start + listPageSize
+	if end >= len(names) {
+		return names[start:], "", nil
+	}
+	return names[start:end], base64.RawURLEncoding.EncodeToString([]byte(names[end-1])), nil
+}
+
+// listChangedDelay is how long registry changes are collected before clients
+// are notified, so registering a whole service sends one notification.
+const listChangedDelay = 100 * time.Millisecond
+
+// toolsChanged schedules notifications/tools/list_changed for every session.
+func (s *Server) toolsChanged() {
+	s.listChanged(mcp.NotificationToolsListChanged)
+}
+
+// listChanged schedules the given list_changed notification for every session.
+func (s *Server) listChanged(method string) {
+	s.listChangedMu.Lock()
+	defer s.listChangedMu.Unlock()
+	if s.listChangedPending[method] {
+		return
+	}
+	s.listChangedPending[method] = true
+	time.AfterFunc(listChangedDelay, func() {
+		s.listChangedMu.Lock()
+		delete(s.listChangedPending, method)
+		s.listChangedMu.Unlock()
+		s.broadcast(method, nil)
+	})
+}
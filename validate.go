@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/santoshkal/gomcp/pkg/reg"
+)
+
+// runValidate implements "gomcp validate [-plugins] [file...]". It checks each
+// configuration file, or the one the server would load when none is given,
+// and returns the process exit code.
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	loadPlugins := fs.Bool("plugins", false, "Also load every plugin and check its handler signatures")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s validate [-plugins] [file...]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{reg.ResolveConfigPath()}
+	}
+
+	// Plugin loading prints to stdout, which is kept for the results.
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	defer func() { os.Stdout = stdout }()
+
+	code := 0
+	for _, path := range paths {
+		if err := reg.ValidateConfig(path, *loadPlugins); err != nil {
+			fmt.Fprintln(stdout, err)
+			code = 1
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: OK\n", path)
+	}
+	return code
+}